@@ -0,0 +1,90 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/sagan/ptool/constants"
+)
+
+const (
+	// Samples older than this are dropped from AvailabilityStore.
+	AVAILABILITY_SAMPLES_MAX_AGE = 7 * 86400
+	// Max number of samples kept per torrent in AvailabilityStore.
+	AVAILABILITY_SAMPLES_MAX = 100
+)
+
+type AvailabilitySample struct {
+	Time         int64   `json:"time"` // unix timestamp (seconds)
+	Availability float64 `json:"availability"`
+}
+
+// Persisted availability samples of torrents across polls, used by AvailabilityTrend.
+// It's a json file of info-hash => samples (oldest first).
+type AvailabilityStore struct {
+	filename string
+	Samples  map[string][]*AvailabilitySample
+}
+
+// Load store from filename. A non-existent file is treated as an empty store.
+func LoadAvailabilityStore(filename string) (*AvailabilityStore, error) {
+	store := &AvailabilityStore{filename: filename, Samples: map[string][]*AvailabilitySample{}}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return store, nil
+		}
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &store.Samples); err != nil {
+		return nil, fmt.Errorf("invalid availability store file %s: %w", filename, err)
+	}
+	return store, nil
+}
+
+func (store *AvailabilityStore) Save() error {
+	data, err := json.Marshal(store.Samples)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(store.filename, data, constants.PERM)
+}
+
+// Record current availability of torrents into store at now, dropping expired samples,
+// and return each torrent's availability change rate (per day), computed from it's oldest and latest samples.
+// A negative rate means swarm of torrent is shrinking; if availability also drops toward 1,
+// self client is becoming the last seeder and should keep seeding it.
+// Torrents with unknown availability or less than 2 samples spanning some time are not included in result.
+// Call store.Save to persist the recorded samples.
+func AvailabilityTrend(store *AvailabilityStore, torrents []*Torrent, now int64) map[string]float64 {
+	for infoHash, samples := range store.Samples {
+		for len(samples) > 0 && samples[0].Time < now-AVAILABILITY_SAMPLES_MAX_AGE {
+			samples = samples[1:]
+		}
+		if len(samples) == 0 {
+			delete(store.Samples, infoHash)
+		} else {
+			store.Samples[infoHash] = samples
+		}
+	}
+	trends := map[string]float64{}
+	for _, torrent := range torrents {
+		if torrent.Availability < 0 {
+			continue
+		}
+		samples := append(store.Samples[torrent.InfoHash],
+			&AvailabilitySample{Time: now, Availability: torrent.Availability})
+		if len(samples) > AVAILABILITY_SAMPLES_MAX {
+			samples = samples[len(samples)-AVAILABILITY_SAMPLES_MAX:]
+		}
+		store.Samples[torrent.InfoHash] = samples
+		oldest, latest := samples[0], samples[len(samples)-1]
+		if latest.Time > oldest.Time {
+			trends[torrent.InfoHash] = (latest.Availability - oldest.Availability) /
+				float64(latest.Time-oldest.Time) * 86400
+		}
+	}
+	return trends
+}
@@ -0,0 +1,177 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Kinds of modification that BatchingClient buffers. Batches are flushed in this order.
+const (
+	batchCategory   = "category"
+	batchAddTags    = "addTags"
+	batchRemoveTags = "removeTags"
+	batchPause      = "pause"
+	batchResume     = "resume"
+)
+
+var batchKinds = []string{batchCategory, batchAddTags, batchRemoveTags, batchPause, batchResume}
+
+// A pending bulk modification: same kind and same value (e.g. category name or tags list) of many torrents.
+type modifyBatch struct {
+	value      string
+	tags       []string
+	infoHashes []string
+}
+
+// A Client decorator that coalesces ModifyTorrent calls into bulk calls (SetTorrentsCatetory,
+// AddTagsToTorrents, RemoveTagsFromTorrents, PauseTorrents, ResumeTorrents).
+// Only category, tags and pause / resume modifications are buffered; ModifyTorrent calls with any other
+// option (name, meta, speed or share limits...) flush pending batches and then are passed to inner client.
+// Pending modifications are flushed after flushInterval since the first of them, or when Flush / Close is called.
+// Reads (e.g. GetTorrents) do NOT see pending modifications, call Flush in ahead if needed.
+type BatchingClient struct {
+	Client
+	flushInterval time.Duration
+	mu            sync.Mutex
+	batches       map[string][]*modifyBatch // kind => batches
+	pending       map[string]bool           // "kind:infoHash" => true
+	timer         *time.Timer
+	flushErr      error // error of last timer triggered flush, reported by next Flush
+}
+
+func NewBatchingClient(inner Client, flushInterval time.Duration) *BatchingClient {
+	return &BatchingClient{
+		Client:        inner,
+		flushInterval: flushInterval,
+		batches:       map[string][]*modifyBatch{},
+		pending:       map[string]bool{},
+	}
+}
+
+func (bc *BatchingClient) ModifyTorrent(infoHash string, option *TorrentOption, meta map[string]int64) error {
+	if option == nil {
+		option = &TorrentOption{}
+	}
+	if len(meta) > 0 || !isBatchableOption(option) {
+		if err := bc.Flush(); err != nil {
+			return err
+		}
+		return bc.Client.ModifyTorrent(infoHash, option, meta)
+	}
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	var ops [][2]string // kind, value
+	if option.Category != "" {
+		ops = append(ops, [2]string{batchCategory, option.Category})
+	}
+	if len(option.Tags) > 0 {
+		ops = append(ops, [2]string{batchAddTags, strings.Join(option.Tags, ",")})
+	}
+	if len(option.RemoveTags) > 0 {
+		ops = append(ops, [2]string{batchRemoveTags, strings.Join(option.RemoveTags, ",")})
+	}
+	if option.Pause {
+		ops = append(ops, [2]string{batchPause, ""})
+	} else if option.Resume {
+		ops = append(ops, [2]string{batchResume, ""})
+	}
+	// Modifying the same attribute of a torrent twice must keep the order, so flush the former one first.
+	if slices.ContainsFunc(ops, func(op [2]string) bool { return bc.pending[op[0]+":"+infoHash] }) ||
+		(option.Pause && bc.pending[batchResume+":"+infoHash]) || (option.Resume && bc.pending[batchPause+":"+infoHash]) {
+		if err := bc.flush(); err != nil {
+			return err
+		}
+	}
+	for _, op := range ops {
+		kind, value := op[0], op[1]
+		index := slices.IndexFunc(bc.batches[kind], func(b *modifyBatch) bool { return b.value == value })
+		if index == -1 {
+			batch := &modifyBatch{value: value}
+			if kind == batchAddTags {
+				batch.tags = option.Tags
+			} else if kind == batchRemoveTags {
+				batch.tags = option.RemoveTags
+			}
+			bc.batches[kind] = append(bc.batches[kind], batch)
+			index = len(bc.batches[kind]) - 1
+		}
+		bc.batches[kind][index].infoHashes = append(bc.batches[kind][index].infoHashes, infoHash)
+		bc.pending[kind+":"+infoHash] = true
+	}
+	if len(ops) > 0 && bc.timer == nil {
+		bc.timer = time.AfterFunc(bc.flushInterval, func() {
+			bc.mu.Lock()
+			defer bc.mu.Unlock()
+			bc.timer = nil
+			if err := bc.flush(); err != nil {
+				log.Errorf("Failed to flush batched modifications of client %s: %v", bc.GetName(), err)
+				bc.flushErr = err
+			}
+		})
+	}
+	return nil
+}
+
+// Issue all pending modifications now. Also return the error of a previous timer triggered flush, if any.
+func (bc *BatchingClient) Flush() error {
+	bc.mu.Lock()
+	defer bc.mu.Unlock()
+	err := errors.Join(bc.flushErr, bc.flush())
+	bc.flushErr = nil
+	return err
+}
+
+// Flush pending modifications and close inner client.
+func (bc *BatchingClient) Close() {
+	if err := bc.Flush(); err != nil {
+		log.Errorf("Failed to flush batched modifications of client %s: %v", bc.GetName(), err)
+	}
+	bc.Client.Close()
+}
+
+// Must be called with mu held.
+func (bc *BatchingClient) flush() error {
+	if bc.timer != nil {
+		bc.timer.Stop()
+		bc.timer = nil
+	}
+	batches := bc.batches
+	bc.batches = map[string][]*modifyBatch{}
+	bc.pending = map[string]bool{}
+	var errs []error
+	for _, kind := range batchKinds {
+		for _, batch := range batches[kind] {
+			var err error
+			switch kind {
+			case batchCategory:
+				err = bc.Client.SetTorrentsCatetory(batch.infoHashes, batch.value)
+			case batchAddTags:
+				err = bc.Client.AddTagsToTorrents(batch.infoHashes, batch.tags)
+			case batchRemoveTags:
+				err = bc.Client.RemoveTagsFromTorrents(batch.infoHashes, batch.tags)
+			case batchPause:
+				err = bc.Client.PauseTorrents(batch.infoHashes)
+			case batchResume:
+				err = bc.Client.ResumeTorrents(batch.infoHashes)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to %s %q of %d torrents: %w",
+					kind, batch.value, len(batch.infoHashes), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Return true if option contains only modifications that BatchingClient can buffer.
+func isBatchableOption(option *TorrentOption) bool {
+	return option.Name == "" && option.SavePath == "" && option.DownloadSpeedLimit == 0 &&
+		option.UploadSpeedLimit == 0 && option.RatioLimit == 0 && option.SeedingTimeLimit == 0 &&
+		!option.SequentialDownload && !option.SkipChecking
+}
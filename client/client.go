@@ -1,6 +1,10 @@
 package client
 
 import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -9,7 +13,9 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/anacrolix/torrent/metainfo"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/term"
 
@@ -47,31 +53,46 @@ var TrackerValidityInfos = []*TrackerValidityInfoStruct{
 // @todo: considering changing it to interface
 type Torrent struct {
 	InfoHash           string
+	InfoHashV1         string // v1 info-hash. "" if unknown or it's a pure v2 torrent
+	InfoHashV2         string // v2 info-hash. "" if unknown or it's a v1 only torrent
 	Name               string
 	TrackerDomain      string // e.g. tracker.m-team.cc
 	TrackerBaseDomain  string // e.g. m-team.cc
 	Tracker            string
-	State              string // simplified state: seeding|downloading|completed|paused|checking|error|unknown
-	LowLevelState      string // original state value returned by bt client
+	State              string // simplified state: seeding|downloading|completed|paused|checking|moving|error|unknown
+	LowLevelState      string // raw (native) state value returned by bt client. E.g. qb "forcedUP" / "stalledUP"
 	Atime              int64  // timestamp torrent added
 	Ctime              int64  // timestamp torrent completed. <=0 if not completed.
 	ActivityTime       int64  // timestamp of torrent latest activity (a chunk being downloaded / uploaded)
 	Category           string
 	SavePath           string
-	ContentPath        string
+	ContentPath        string // final content path (in SavePath)
+	DownloadPath       string // current content path if incomplete and in a separate incomplete dir, else =ContentPath
 	Tags               []string
 	Downloaded         int64
 	DownloadSpeed      int64
-	DownloadSpeedLimit int64 // -1 means no limit
+	DownloadSpeedLimit int64 // -1 means no per-torrent limit (global limit still applies). Never "unknown"
 	Uploaded           int64
 	UploadSpeed        int64
-	UploadedSpeedLimit int64 // -1 means no limit
+	UploadSpeedLimit   int64 // -1 means no per-torrent limit (global limit still applies). Never "unknown"
 	Size               int64 // size of torrent files that selected for downloading
 	SizeTotal          int64 // Total size of all file in the torrent (including unselected ones)
 	SizeCompleted      int64
 	Seeders            int64 // Cnt of seeders (including self client, if it's seeding), returned by tracker
 	Leechers           int64
+	ConnectedPeers     int64   // number of peers (seeds + leechers) currently connected to
+	Availability       float64 // distributed copies of torrent among connected peers (qb). -1 if unknown
+	Ratio              float64 // share ratio (Uploaded / Downloaded). INFINITE_RATIO if Downloaded == 0
+	PieceSize          int64   // piece size (bytes) of torrent. 0 if unknown (qb), see LoadPieceGeometry
+	PieceCount         int64   // number of pieces of torrent. 0 if unknown
+	FilesTotal         int64   // number of files in torrent. 0 if unknown. See LoadFilesStats
+	FilesComplete      int64   // number of fully downloaded files. Valid only if FilesTotal > 0
+	Priority           int64   // queue position of torrent, 1 is the highest. 0 if unknown or not queued
+	NextAnnounceTime   int64   // timestamp of next scheduled tracker announce. 0 if unknown. See LoadNextAnnounceTime
 	Meta               map[string]int64
+	Comment            string // comment of torrent (.torrent file). Not all clients report it
+	SourceUrl          string // url where the torrent was obtained (parsed from comment), "" if unknown
+	contentId          string // cache of ContentID
 }
 
 type TorrentContentFile struct {
@@ -85,6 +106,7 @@ type TorrentContentFile struct {
 
 type Status struct {
 	FreeSpaceOnDisk           int64 // -1 means unknown
+	DiskTotal                 int64 // total size of the disk of default save path. -1 if unknown
 	UnfinishedSize            int64
 	UnfinishedDownloadingSize int64
 	DownloadSpeed             int64
@@ -93,12 +115,51 @@ type Status struct {
 	UploadSpeedLimit          int64 // <= 0 means no limit
 	NoAdd                     bool  // if true, brush and other tasks will NOT add any torrent to client
 	NoDel                     bool  // if true, brush and other tasks will NOT delete any torrent from client
+	TotalPeers                int64 // Cnt of peers connected, summed across torrents. 0 if unknown (tr)
+	TotalConnections          int64 // Cnt of global peer connections. 0 if unknown
+	AllTimeDownloaded         int64 // Cumulative downloaded bytes of client. 0 if unknown
+	AllTimeUploaded           int64 // Cumulative uploaded bytes of client. 0 if unknown
+}
+
+// Return free disk space as a percentage (0-100) of disk total size, or -1 if either is unknown.
+func (status *Status) FreeSpacePercent() float64 {
+	if status.DiskTotal <= 0 || status.FreeSpaceOnDisk < 0 {
+		return -1
+	}
+	return float64(status.FreeSpaceOnDisk) * 100 / float64(status.DiskTotal)
+}
+
+// An entry of client's own log.
+type LogEntry struct {
+	Id      int64
+	Time    int64  // unix timestamp (seconds)
+	Level   string // normal|info|warning|critical
+	Message string
+}
+
+// Schedule of client's alternative speed limits.
+type SchedulerConfig struct {
+	Enabled bool
+	From    int64          // minutes after midnight when alternative speed limits turn on
+	To      int64          // minutes after midnight when alternative speed limits turn off
+	Days    []time.Weekday // days when schedule applies, sorted. Empty means every day
 }
 
 type TorrentTracker struct {
-	Status string //working|notcontacted|error|updating|disabled|unknown
-	Url    string
-	Msg    string
+	Status   string //working|notcontacted|error|updating|disabled|unknown
+	Url      string
+	Msg      string
+	Seeders  int64 // number of seeders reported by tracker. -1 if unknown
+	Leechers int64 // number of leechers reported by tracker. -1 if unknown
+	Peers    int64 // number of peers received from tracker in last announce. -1 if unknown
+	// timestamp of next scheduled announce to this tracker. 0 if unknown (qb)
+	NextAnnounceTime int64
+}
+
+// A torrent with trackers info. See GetTorrentsWithTrackerStats.
+type TorrentWithTrackers struct {
+	*Torrent
+	Trackers TorrentTrackers
 }
 
 type TorrentTrackers []TorrentTracker
@@ -106,17 +167,35 @@ type TorrentTrackers []TorrentTracker
 type TorrentOption struct {
 	Name               string // if not empty, set name of torrent in client to this value
 	Category           string
-	SavePath           string
+	SavePath           string // In AddTorrent, it can be a template. See torrentutil.ResolveSavePath
 	Tags               []string
 	RemoveTags         []string // used only in ModifyTorrent
 	DownloadSpeedLimit int64
 	UploadSpeedLimit   int64
 	RatioLimit         float64 // If > 0, will stop seeding after ratio (up/dl) exceeds this value
 	SeedingTimeLimit   int64   // If > 0, will stop seeding after be seeded for this time (seconds)
+	// Skip hash checking and mark torrent as complete when adding, for data that already exists on disk.
+	// Use it only if the data is trusted: corrupted or incomplete data will be seeded as complete.
+	// Only qb supports it; other clients ignore it.
 	SkipChecking       bool
 	Pause              bool
 	Resume             bool // use only in ModifyTorrent, to start a paused torrent
 	SequentialDownload bool // qb only
+	// Used only in AddTorrent. qb only. Whether to create a subfolder for torrent contents:
+	// "original" (default): keep torrent's original layout; "subfolder": always create subfolder;
+	// "nosubfolder": never create subfolder (strip torrent's root folder).
+	ContentLayout string
+	// If not empty, record it as source url of torrent into the comment field of .torrent file when adding.
+	// used only in AddTorrent, and only takes effect if torrent contents is a .torrent file (not url)
+	SourceUrl string
+}
+
+// Default options the client applies to torrents added without explicit options. See Client.GetAddDefaults.
+type AddDefaults struct {
+	SavePath      string
+	Category      string // "" if client has no default category (neither qb nor transmission has it)
+	Paused        bool   // true if added torrents are paused (not started) by default
+	ContentLayout string // "original" | "subfolder" | "nosubfolder". See TorrentOption.ContentLayout. "" if unknown
 }
 
 type TorrentCategory struct {
@@ -156,10 +235,24 @@ type Client interface {
 	MakeCategory(category string, savePath string) error
 	DeleteCategories(categories []string) error
 	GetCategories() ([]*TorrentCategory, error)
+	// Return the save path of torrents in the category. Return an error if category does not exist.
+	GetCategorySavePath(category string) (string, error)
+	// Return category => save path of all categories in one call. Save path is "" if client does not have
+	// per-category save paths (transmission).
+	GetCategorySavePaths() (map[string]string, error)
 	SetTorrentsCatetory(infoHashes []string, category string) error
+	// Enable / disable Automatic Torrent Management (torrent save path follows it's category). QB only.
+	SetTorrentsAutoManagement(infoHashes []string, enabled bool) error
 	SetAllTorrentsCatetory(category string) error
 	SetTorrentsShareLimits(infoHashes []string, ratioLimit float64, seedingTimeLimit int64) error
 	SetAllTorrentsShareLimits(ratioLimit float64, seedingTimeLimit int64) error
+	// Set per-torrent speed limits (bytes/s). 0 means do not change, -1 means no limit.
+	SetTorrentsSpeedLimit(infoHashes []string, downloadSpeedLimit int64, uploadSpeedLimit int64) error
+	// Move torrent to the priority (position) in queue, 1 is the highest. See Torrent.Priority.
+	SetTorrentPriority(infoHash string, priority int64) error
+	// Overwrite the comment of torrents. Return ErrUnsupported if client does not allow editing comments.
+	// Torrent info-hash does not change. Read it via Torrent.Comment.
+	SetTorrentsComment(infoHashes []string, comment string) error
 	TorrentRootPathExists(rootFolder string) bool
 	GetTorrentContents(infoHash string) ([]*TorrentContentFile, error)
 	PurgeCache()
@@ -169,11 +262,43 @@ type Client interface {
 	SetConfig(variable string, value string) error
 	GetConfig(variable string) (string, error)
 	GetTorrentTrackers(infoHash string) (TorrentTrackers, error)
+	// Return infoHash => trackers of torrents. tr fetches them in one request;
+	// qb does not have a batch API, so it makes one request per torrent.
+	GetTorrentsTrackers(infoHashes []string) (map[string]TorrentTrackers, error)
 	EditTorrentTracker(infoHash string, oldTracker string, newTracker string, replaceHost bool) error
 	AddTorrentTrackers(infoHash string, trackers []string, oldTracker string, removeExisting bool) error
 	RemoveTorrentTrackers(infoHash string, trackers []string) error
 	// QB only, priority: 0	Do not download; 1	Normal priority; 6	High priority; 7	Maximal priority
 	SetFilePriority(infoHash string, fileIndexes []int64, priority int64) error
+	// Ask client to flush it's in-memory state to disk, e.g. before backing up client data.
+	// Transmission: only saves session settings (settings.json), via an empty session-set;
+	// per-torrent resume files are NOT flushed.
+	// qBittorrent: no such API, always return ErrUnsupported.
+	FlushState() error
+	// Set client's peer connection encryption mode, which must be one of ENCRYPTION_MODES.
+	SetEncryptionMode(mode string) error
+	// Return client's peer connection encryption mode, which is one of ENCRYPTION_MODES.
+	GetEncryptionMode() (string, error)
+	// Return banned peer IPs of client.
+	GetBannedPeers() ([]string, error)
+	// Ban peers permanently. Each peer is in "host:port" format.
+	BanPeers(peers []string) error
+	// Return client's log entries which id > lastKnownId, and the max id of returned entries
+	// (lastKnownId if no new entry). Use -1 as lastKnownId to get all entries.
+	GetLog(lastKnownId int64) ([]*LogEntry, int64, error)
+	// Get / set global peer discovery (DHT / PeX / LSD) toggles.
+	GetGlobalPeerDiscovery() (dht bool, pex bool, lsd bool, err error)
+	SetGlobalPeerDiscovery(dht bool, pex bool, lsd bool) error
+	// Get / set the schedule of alternative speed limits.
+	GetScheduler() (*SchedulerConfig, error)
+	SetScheduler(scheduler *SchedulerConfig) error
+	// Get / set the global max number of upload slots (concurrent uploads), -1 means unlimited.
+	// It's different from the queueing max active uploads. QB only.
+	GetGlobalMaxUploads() (int, error)
+	SetGlobalMaxUploads(n int) error
+	// Return the options applied to torrents added without explicit options, e.g. to preview where a torrent
+	// will be saved.
+	GetAddDefaults() (*AddDefaults, error)
 	Cached() bool
 	Close()
 }
@@ -181,13 +306,24 @@ type Client interface {
 type RegInfo struct {
 	Name    string
 	Creator func(string, *config.ClientConfigStruct, *config.ConfigStruct) (Client, error)
+	// Optional. Check client config without making any network request.
+	Validator func(*config.ClientConfigStruct) error
 }
 
 type ClientCreator func(*RegInfo) (Client, error)
 
+// Torrent.Ratio value of torrents that have nothing downloaded. Same as qb's max ratio value.
+const INFINITE_RATIO = float64(9999)
+
 var (
-	STATES             = []string{"seeding", "downloading", "completed", "paused", "checking", "error", "unknown"}
-	STATE_FILTERS      = []string{"_all", "_active", "_done", "_undone"}
+	// Returned by client methods that are not supported by current client (type).
+	ErrUnsupported = errors.New("unsupported")
+	// Returned by ClientsShareStorage if client save path is not accessible from local filesystem.
+	ErrStorageNotAccessible = errors.New("client save path is not accessible from local filesystem")
+	STATES                  = []string{"seeding", "downloading", "completed", "paused", "checking", "moving", "error", "unknown"}
+	STATE_FILTERS           = []string{"_all", "_active", "_done", "_undone"}
+	// Peer connection encryption modes: prefer encryption / require encryption / disable encryption.
+	ENCRYPTION_MODES   = []string{"prefer", "require", "disable"}
 	Registry           = []*RegInfo{}
 	substituteTagRegex = regexp.MustCompile(`^(category|meta\..+):.+$`)
 	// all clientInstances created during this ptool program session
@@ -283,6 +419,34 @@ func ClientExists(name string) bool {
 	return clientConfig != nil
 }
 
+// Check all clients of config: name must be non-empty and unique, type must be supported,
+// and config must pass the per-type Validator. It does NOT make network requests. Return all found problems.
+func ValidateAllClients(cfg *config.ConfigStruct) []error {
+	var errs []error
+	names := map[string]bool{}
+	for i, clientConfig := range cfg.Clients {
+		if clientConfig.Name == "" {
+			errs = append(errs, fmt.Errorf("client #%d: name is empty", i))
+			continue
+		}
+		if names[clientConfig.Name] {
+			errs = append(errs, fmt.Errorf("client %s: duplicate name", clientConfig.Name))
+		}
+		names[clientConfig.Name] = true
+		regInfo, err := Find(clientConfig.Type)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("client %s: unsupported type %q", clientConfig.Name, clientConfig.Type))
+			continue
+		}
+		if regInfo.Validator != nil {
+			if err := regInfo.Validator(clientConfig); err != nil {
+				errs = append(errs, fmt.Errorf("client %s: %w", clientConfig.Name, err))
+			}
+		}
+	}
+	return errs
+}
+
 func CreateClient(name string) (Client, error) {
 	if clients[name] != nil {
 		return clients[name], nil
@@ -343,6 +507,55 @@ func ParseMetaFromName(fullname string) (name string, meta map[string]int64) {
 	return
 }
 
+// Parse torrent source url from comment. The comment itself could be an url
+// (e.g. torrent details page url, which many sites put in the comment field),
+// or a json (ptool TorrentCommentMeta) with "source_url" field.
+func ParseSourceUrlFromComment(comment string) string {
+	comment = strings.TrimSpace(comment)
+	if util.IsUrl(comment) {
+		return comment
+	}
+	var commentMeta struct {
+		SourceUrl string `json:"source_url"`
+	}
+	if json.Unmarshal([]byte(comment), &commentMeta) == nil {
+		return commentMeta.SourceUrl
+	}
+	return ""
+}
+
+// Record sourceUrl into the comment of torrent contents, return the updated .torrent file contents.
+// If the comment is empty, it's set to sourceUrl. Otherwise it's encoded to a json (compatible with ptool
+// TorrentCommentMeta) with original comment preserved. Torrent info-hash does not change.
+func SetTorrentContentSourceUrl(torrentContent []byte, sourceUrl string) ([]byte, error) {
+	metaInfo, err := metainfo.Load(bytes.NewReader(torrentContent))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse torrent: %w", err)
+	}
+	if ParseSourceUrlFromComment(metaInfo.Comment) == sourceUrl {
+		return torrentContent, nil
+	}
+	if metaInfo.Comment == "" {
+		metaInfo.Comment = sourceUrl
+	} else {
+		commentMeta := map[string]any{}
+		if json.Unmarshal([]byte(metaInfo.Comment), &commentMeta) != nil {
+			commentMeta = map[string]any{"comment": metaInfo.Comment}
+		}
+		commentMeta["source_url"] = sourceUrl
+		data, err := json.Marshal(commentMeta)
+		if err != nil {
+			return nil, err
+		}
+		metaInfo.Comment = string(data)
+	}
+	buf := &bytes.Buffer{}
+	if err = metaInfo.Write(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 func (torrent *Torrent) MatchFilter(filter string) bool {
 	if filter == "" || util.ContainsI(torrent.Name, filter) {
 		return true
@@ -368,6 +581,88 @@ func (torrent *Torrent) MatchTracker(tracker string) bool {
 	return torrent.TrackerDomain == tracker
 }
 
+// Return share ratio with two decimals, or "∞" if torrent has nothing downloaded.
+// Ratio >= 1000 is displayed as ">999", so the text is at most 6 chars wide.
+func (torrent *Torrent) RatioText() string {
+	if torrent.Downloaded == 0 {
+		return "∞"
+	}
+	if text := fmt.Sprintf("%.2f", torrent.Ratio); len(text) <= 6 {
+		return text
+	}
+	return ">999"
+}
+
+// Populate FilesTotal and FilesComplete from torrent contents fetched from client, if they are unknown.
+// Clients usually do not report them in torrents list, as it's expensive.
+func (torrent *Torrent) LoadFilesStats(clientInstance Client) error {
+	if torrent.FilesTotal > 0 {
+		return nil
+	}
+	files, err := clientInstance.GetTorrentContents(torrent.InfoHash)
+	if err != nil {
+		return fmt.Errorf("failed to get torrent contents: %w", err)
+	}
+	torrent.FilesTotal = int64(len(files))
+	torrent.FilesComplete = 0
+	for _, file := range files {
+		if file.Complete {
+			torrent.FilesComplete++
+		}
+	}
+	return nil
+}
+
+// Load PieceSize & PieceCount of torrent by parsing it's exported .torrent file, if client does not report them.
+// It's not done by GetTorrent(s) as it requires an extra request per torrent.
+func (torrent *Torrent) LoadPieceGeometry(clientInstance Client) error {
+	if torrent.PieceSize > 0 {
+		return nil
+	}
+	torrentContent, err := clientInstance.ExportTorrentFile(torrent.InfoHash)
+	if err != nil {
+		return fmt.Errorf("failed to export torrent: %w", err)
+	}
+	metaInfo, err := metainfo.Load(bytes.NewReader(torrentContent))
+	if err != nil {
+		return fmt.Errorf("failed to parse torrent: %w", err)
+	}
+	info, err := metaInfo.UnmarshalInfo()
+	if err != nil {
+		return fmt.Errorf("failed to parse torrent info: %w", err)
+	}
+	torrent.PieceSize = info.PieceLength
+	torrent.PieceCount = int64(info.NumPieces())
+	return nil
+}
+
+// Load NextAnnounceTime of torrent from it's trackers, if client does not report it in torrents list (tr).
+func (torrent *Torrent) LoadNextAnnounceTime(clientInstance Client) error {
+	if torrent.NextAnnounceTime > 0 {
+		return nil
+	}
+	trackers, err := clientInstance.GetTorrentTrackers(torrent.InfoHash)
+	if err != nil {
+		return fmt.Errorf("failed to get torrent trackers: %w", err)
+	}
+	for _, tracker := range trackers {
+		if tracker.NextAnnounceTime > 0 &&
+			(torrent.NextAnnounceTime == 0 || tracker.NextAnnounceTime < torrent.NextAnnounceTime) {
+			torrent.NextAnnounceTime = tracker.NextAnnounceTime
+		}
+	}
+	return nil
+}
+
+// Return Uploaded / Size, i.e. how many times the torrent's size has been uploaded. Unlike share ratio,
+// it's meaningful for torrents with (near) zero Downloaded, e.g. freeleech or xseed ones. 0 if Size is 0.
+func (torrent *Torrent) UploadEfficiency() float64 {
+	if torrent.Size <= 0 {
+		return 0
+	}
+	return float64(torrent.Uploaded) / float64(torrent.Size)
+}
+
 func (torrent *Torrent) StateIconText() string {
 	s := ""
 	showProcess := false
@@ -388,6 +683,8 @@ func (torrent *Torrent) StateIconText() string {
 		} else {
 			s = "→↓"
 		}
+	case "moving":
+		s = "»"
 	case "error":
 		s = "!"
 		showProcess = true
@@ -416,6 +713,14 @@ func (torrent *Torrent) GetSiteFromTag() string {
 	return torrent.GetMetaFromTag("site")
 }
 
+// Return site name of torrent, from "site:<name>" tag, or derived from tracker domain using config.
+func (torrent *Torrent) GetSite() string {
+	if site := torrent.GetSiteFromTag(); site != "" {
+		return site
+	}
+	return config.SiteForTracker(torrent.TrackerDomain)
+}
+
 func (torrent *Torrent) GetMetaFromTag(meta string) string {
 	for _, tag := range torrent.Tags {
 		if strings.HasPrefix(tag, meta+":") {
@@ -445,6 +750,22 @@ func (torrent *Torrent) RemoveSubstituteTags() {
 	})
 }
 
+// Return true if torrent has a per-torrent download or upload speed limit set (manually throttled),
+// instead of "no limit" (-1) which inherits the global limits.
+func (torrent *Torrent) HasCustomSpeedLimit() bool {
+	return torrent.DownloadSpeedLimit >= 0 || torrent.UploadSpeedLimit >= 0
+}
+
+// Return true if client is relocating torrent data. Acting on a moving torrent (e.g. deleting) is dangerous.
+func (torrent *Torrent) IsMoving() bool {
+	return torrent.State == "moving"
+}
+
+// Return true if torrent was added by ptool (has config.AUTOMATION_TAG tag), rather than added manually.
+func (torrent *Torrent) IsPtoolManaged() bool {
+	return torrent.HasTag(config.AUTOMATION_TAG)
+}
+
 func (torrent *Torrent) IsComplete() bool {
 	return torrent.SizeCompleted == torrent.Size
 }
@@ -488,6 +809,38 @@ func GenerateTorrentTagFromSite(site string) string {
 	return "site:" + site
 }
 
+// If tags does not contain a "site:" tag, derive it from the tracker of torrent contents
+// using config.SiteForTracker and append it to tags. Return the (possibly) updated tags.
+// It does nothing if torrent contents is a url.
+func DeriveSiteTag(torrentContent []byte, tags []string) []string {
+	if slices.ContainsFunc(tags, func(tag string) bool { return strings.HasPrefix(tag, "site:") }) ||
+		util.IsTorrentUrl(string(torrentContent)) {
+		return tags
+	}
+	metaInfo, err := metainfo.Load(bytes.NewReader(torrentContent))
+	if err != nil {
+		return tags
+	}
+	tracker := metaInfo.Announce
+	if tracker == "" && len(metaInfo.AnnounceList) > 0 && len(metaInfo.AnnounceList[0]) > 0 {
+		tracker = metaInfo.AnnounceList[0][0]
+	}
+	if site := config.SiteForTracker(util.ParseUrlHostname(tracker)); site != "" {
+		tags = append(slices.Clone(tags), GenerateTorrentTagFromSite(site))
+	}
+	return tags
+}
+
+// Return the tags of a torrent to be added to client: tags with derived site tag (see DeriveSiteTag)
+// and config.AUTOMATION_TAG appended.
+func GenerateAddTorrentTags(torrentContent []byte, tags []string) []string {
+	tags = DeriveSiteTag(torrentContent, tags)
+	if !slices.Contains(tags, config.AUTOMATION_TAG) {
+		tags = append(slices.Clone(tags), config.AUTOMATION_TAG)
+	}
+	return tags
+}
+
 func GenerateTorrentTagFromCategory(category string) string {
 	return "category:" + category
 }
@@ -565,22 +918,50 @@ func (torrent *Torrent) Print() {
 	fmt.Printf("- Completion time: %s\n", ctimeStr)
 	fmt.Printf("- Last activity time: %s\n", util.FormatTime(torrent.ActivityTime))
 	fmt.Printf("- Tracker: %s\n", torrent.Tracker)
+	if torrent.NextAnnounceTime > 0 {
+		fmt.Printf("- Next announce time: %s\n", util.FormatTime(torrent.NextAnnounceTime))
+	}
 	fmt.Printf("- Seeders / Peers: %d / %d\n", torrent.Seeders, torrent.Leechers)
 	fmt.Printf("- Save path: %s\n", torrent.SavePath)
 	fmt.Printf("- Content path: %s\n", torrent.ContentPath)
+	if torrent.DownloadPath != torrent.ContentPath {
+		fmt.Printf("- Download path: %s\n", torrent.DownloadPath)
+	}
+	fmt.Printf("- Source url: %s\n", torrent.SourceUrl)
+	if torrent.FilesTotal > 0 {
+		fmt.Printf("- Files complete / total: %d / %d\n", torrent.FilesComplete, torrent.FilesTotal)
+	}
+	if torrent.PieceSize > 0 {
+		fmt.Printf("- Pieces: %d x %s\n", torrent.PieceCount, util.BytesSize(float64(torrent.PieceSize)))
+	}
 	fmt.Printf("- Downloaded / Uploaded: %s / %s\n",
 		util.BytesSize(float64(torrent.Downloaded)),
 		util.BytesSize(float64(torrent.Uploaded)),
 	)
 }
 
+type PrintTorrentsOptions struct {
+	Filter    string // only print torrents which name contains it
+	ShowSum   int64  // 0 - no; 1 - yes; 2 - sum only
+	Dense     bool   // print full name, category, tags & content path
+	ShowRatio bool   // print "Ratio" column
+}
+
 // showSum: 0 - no; 1 - yes; 2 - sum only
 func PrintTorrents(output io.Writer, torrents []*Torrent, filter string, showSum int64, dense bool) {
+	PrintTorrentsWithOptions(output, torrents, &PrintTorrentsOptions{Filter: filter, ShowSum: showSum, Dense: dense})
+}
+
+func PrintTorrentsWithOptions(output io.Writer, torrents []*Torrent, options *PrintTorrentsOptions) {
+	filter, showSum, dense := options.Filter, options.ShowSum, options.Dense
 	width, _, _ := term.GetSize(int(os.Stdout.Fd()))
 	if width < config.CLIENT_TORRENTS_WIDTH {
 		width = config.CLIENT_TORRENTS_WIDTH
 	}
 	widthExcludingName := 105 // 40+6+5+6+6+5+5+16+8*2
+	if options.ShowRatio {
+		widthExcludingName += 8
+	}
 	widthName := width - widthExcludingName
 	cnt := int64(0)
 	var cntPaused, cntDownloading, cntSeeding, cntCompleted, cntOthers int64
@@ -589,8 +970,12 @@ func PrintTorrents(output io.Writer, torrents []*Torrent, filter string, showSum
 	largestSize := int64(-1)
 	sizeUnfinished := int64(0)
 	if showSum < 2 {
-		fmt.Fprintf(output, "%-*s  %-40s  %-6s  %-5s  %-6s  %-6s  %-5s  %-5s  %-16s\n",
-			widthName, "Name", "InfoHash", "Size", "State", "↓S(/s)", "↑S(/s)", "Seeds", "Peers", "Tracker")
+		fmt.Fprintf(output, "%-*s  %-40s  %-6s  %-5s  %-6s  %-6s  %-5s  %-5s  ",
+			widthName, "Name", "InfoHash", "Size", "State", "↓S(/s)", "↑S(/s)", "Seeds", "Peers")
+		if options.ShowRatio {
+			fmt.Fprintf(output, "%-6s  ", "Ratio")
+		}
+		fmt.Fprintf(output, "%-16s\n", "Tracker")
 	}
 	for _, torrent := range torrents {
 		if filter != "" && !torrent.MatchFilter(filter) {
@@ -638,7 +1023,7 @@ func PrintTorrents(output io.Writer, torrents []*Torrent, filter string, showSum
 		remain := util.PrintStringInWidth(output, name, int64(widthName), true)
 		// 目前遇到的tracker域名最长的: "wintersakura.net"
 		trackerBaseDomain, _ := util.StringPrefixInWidth(torrent.TrackerBaseDomain, 16)
-		fmt.Fprintf(output, "  %-40s  %-6s  %-5s  %-6s  %-6s  %-5d  %-5d  %-16s\n",
+		fmt.Fprintf(output, "  %-40s  %-6s  %-5s  %-6s  %-6s  %-5d  %-5d  ",
 			torrent.InfoHash,
 			util.BytesSizeAround(float64(torrent.Size)),
 			torrent.StateIconText(),
@@ -646,8 +1031,11 @@ func PrintTorrents(output io.Writer, torrents []*Torrent, filter string, showSum
 			util.BytesSizeAround(float64(torrent.UploadSpeed)),
 			torrent.Seeders,
 			torrent.Leechers,
-			trackerBaseDomain,
 		)
+		if options.ShowRatio {
+			fmt.Fprintf(output, "%-6s  ", torrent.RatioText())
+		}
+		fmt.Fprintf(output, "%-16s\n", trackerBaseDomain)
 		if dense {
 			for {
 				remain = strings.TrimSpace(remain)
@@ -705,6 +1093,10 @@ func DeleteTorrentsAuto(clientInstance Client, infoHashes []string) (err error)
 	var torrents []*Torrent
 	for _, infoHash := range infoHashes {
 		if torrent, _ := clientInstance.GetTorrent(infoHash); torrent != nil {
+			if torrent.IsMoving() {
+				log.Warnf("Skip deleting torrent %s (%s) which is moving", torrent.InfoHash, torrent.Name)
+				continue
+			}
 			torrents = append(torrents, torrent)
 		}
 	}
@@ -865,6 +1257,36 @@ func (torrent *Torrent) MatchStateFilter(stateFilter string) bool {
 	return stateFilter == torrent.State
 }
 
+// Return true if hash (case-insensitive) is torrent's info-hash, in either v1 or v2 form.
+func MatchesHash(torrent *Torrent, hash string) bool {
+	return hash != "" && (strings.EqualFold(torrent.InfoHash, hash) ||
+		strings.EqualFold(torrent.InfoHashV1, hash) || strings.EqualFold(torrent.InfoHashV2, hash))
+}
+
+// Return a tracker-independent id of torrent contents: hex sha1 of sorted file paths and sizes.
+// Cross-seed torrents of the same contents (files) have the same content id, despite different info-hashes.
+// It requires torrent contents, which are fetched from clientInstance on first call; the result is cached.
+func (torrent *Torrent) ContentID(clientInstance Client) (string, error) {
+	if torrent.contentId == "" {
+		files, err := clientInstance.GetTorrentContents(torrent.InfoHash)
+		if err != nil {
+			return "", fmt.Errorf("failed to get torrent contents: %w", err)
+		}
+		torrent.contentId = ContentIDFromFiles(files)
+	}
+	return torrent.contentId, nil
+}
+
+// Compute the content id (see Torrent.ContentID) of torrent contents files.
+func ContentIDFromFiles(files []*TorrentContentFile) string {
+	lines := make([]string, 0, len(files))
+	for _, file := range files {
+		lines = append(lines, fmt.Sprintf("%s\x00%d\n", file.Path, file.Size))
+	}
+	slices.Sort(lines)
+	return fmt.Sprintf("%x", sha1.Sum([]byte(strings.Join(lines, ""))))
+}
+
 var infoHashV1Regex = regexp.MustCompile(`^[0-9a-fA-F]{40}$`)
 var infoHashV2Regex = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
 
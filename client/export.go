@@ -0,0 +1,140 @@
+package client
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sagan/ptool/constants"
+)
+
+// Export .torrent files of client torrents matching the filter (nil matches all) into a zip archive written to w.
+// Entries are named "<name>.<infohash>.torrent". Each .torrent file is written to w as soon as it's exported,
+// so memory usage is bounded regardless of count. Return the number of torrents included.
+func ExportMatchingTorrents(clientInstance Client, f *TorrentFilter, w io.Writer) (count int, err error) {
+	torrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get torrents: %w", err)
+	}
+	torrents = FilterTorrents(torrents, f)
+	zipWriter := zip.NewWriter(w)
+	for _, torrent := range torrents {
+		contents, err := clientInstance.ExportTorrentFile(torrent.InfoHash)
+		if err != nil {
+			zipWriter.Close()
+			return count, fmt.Errorf("failed to export torrent %s: %w", torrent.InfoHash, err)
+		}
+		name := strings.TrimSpace(constants.FilenameRestrictedCharacterReplacer.Replace(torrent.Name))
+		entry, err := zipWriter.Create(fmt.Sprintf("%s.%s.torrent", name, torrent.InfoHash))
+		if err != nil {
+			zipWriter.Close()
+			return count, err
+		}
+		if _, err = entry.Write(contents); err != nil {
+			zipWriter.Close()
+			return count, err
+		}
+		count++
+	}
+	return count, zipWriter.Close()
+}
+
+// Organizational metadata of a client torrent that the .torrent file itself does not carry.
+// Written to a "<infohash>.json" sidecar file next to exported .torrent file.
+type TorrentSidecar struct {
+	InfoHash string           `json:"infohash"`
+	Name     string           `json:"name"`
+	Category string           `json:"category,omitempty"`
+	Tags     []string         `json:"tags,omitempty"`
+	Meta     map[string]int64 `json:"meta,omitempty"`
+	SavePath string           `json:"save_path,omitempty"`
+	Trackers []string         `json:"trackers,omitempty"` // only the current tracker of torrent for now
+	Atime    int64            `json:"atime,omitempty"`
+	Ctime    int64            `json:"ctime,omitempty"`
+}
+
+// Return the option (and meta) to re-add the torrent to client with all it's organizational metadata restored.
+func (sidecar *TorrentSidecar) ToTorrentOption() (*TorrentOption, map[string]int64) {
+	return &TorrentOption{
+		Name:     sidecar.Name,
+		Category: sidecar.Category,
+		Tags:     sidecar.Tags,
+		SavePath: sidecar.SavePath,
+	}, sidecar.Meta
+}
+
+// Write a "<infohash>.json" sidecar file of torrent to dir. See TorrentSidecar.
+func WriteTorrentSidecar(torrent *Torrent, dir string) error {
+	sidecar := &TorrentSidecar{
+		InfoHash: torrent.InfoHash,
+		Name:     torrent.Name,
+		Category: torrent.Category,
+		Tags:     torrent.Tags,
+		Meta:     torrent.Meta,
+		SavePath: torrent.SavePath,
+		Atime:    torrent.Atime,
+		Ctime:    torrent.Ctime,
+	}
+	if torrent.Tracker != "" {
+		sidecar.Trackers = []string{torrent.Tracker}
+	}
+	data, err := json.MarshalIndent(sidecar, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, torrent.InfoHash+".json"), data, constants.PERM)
+}
+
+// Read a sidecar file written by WriteTorrentSidecar.
+func ReadTorrentSidecar(filename string) (*TorrentSidecar, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	sidecar := &TorrentSidecar{}
+	if err = json.Unmarshal(data, sidecar); err != nil {
+		return nil, fmt.Errorf("invalid sidecar file %s: %w", filename, err)
+	}
+	return sidecar, nil
+}
+
+// Reversibly free disk space: export "<infohash>.torrent" file and sidecar file (see WriteTorrentSidecar)
+// of each torrent to sidecarDir, then delete the torrents from client with their files. Torrents can later be
+// re-added using the .torrent file with the option of ReadTorrentSidecar (see TorrentSidecar.ToTorrentOption),
+// after restoring the data. If any torrent fails to be archived, no torrent is deleted.
+// Return the number of archived (and deleted) torrents.
+func ArchiveTorrents(clientInstance Client, infoHashes []string, sidecarDir string) (archived int, err error) {
+	if err = os.MkdirAll(sidecarDir, constants.PERM_DIR); err != nil {
+		return 0, fmt.Errorf("failed to create dir: %w", err)
+	}
+	for _, infoHash := range infoHashes {
+		torrent, err := clientInstance.GetTorrent(infoHash)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get torrent %s: %w", infoHash, err)
+		}
+		if torrent == nil {
+			return 0, fmt.Errorf("torrent %s not found", infoHash)
+		}
+		contents, err := clientInstance.ExportTorrentFile(infoHash)
+		if err != nil {
+			return 0, fmt.Errorf("failed to export torrent %s: %w", infoHash, err)
+		}
+		if err = os.WriteFile(filepath.Join(sidecarDir, infoHash+".torrent"), contents, constants.PERM); err != nil {
+			return 0, fmt.Errorf("failed to write torrent %s: %w", infoHash, err)
+		}
+		if err = WriteTorrentSidecar(torrent, sidecarDir); err != nil {
+			return 0, fmt.Errorf("failed to write sidecar of torrent %s: %w", infoHash, err)
+		}
+	}
+	if len(infoHashes) == 0 {
+		return 0, nil
+	}
+	if err = clientInstance.DeleteTorrents(infoHashes, true); err != nil {
+		return 0, fmt.Errorf("failed to delete torrents: %w", err)
+	}
+	return len(infoHashes), nil
+}
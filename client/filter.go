@@ -0,0 +1,414 @@
+package client
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+// Return torrents which share ratio is in [minRatio, maxRatio]. maxRatio <= 0 means no upper bound.
+// Torrents with Downloaded == 0 (e.g. downloaded from freeleech or added as xseed) have an infinite ratio,
+// they are treated as matching any maxRatio, so they are always included in the result.
+func FilterByRatio(torrents []*Torrent, minRatio, maxRatio float64) []*Torrent {
+	var result []*Torrent
+	for _, torrent := range torrents {
+		if torrent.Downloaded == 0 ||
+			(torrent.Ratio >= minRatio && (maxRatio <= 0 || torrent.Ratio <= maxRatio)) {
+			result = append(result, torrent)
+		}
+	}
+	return result
+}
+
+// Return downloading torrents that have no download speed and no activity for at least minStall.
+// Torrents that never have any activity are measured from their add time.
+func FindStalledDownloads(torrents []*Torrent, minStall time.Duration) []*Torrent {
+	threshold := time.Now().Add(-minStall).Unix()
+	var stalled []*Torrent
+	for _, torrent := range torrents {
+		if torrent.State != "downloading" || torrent.DownloadSpeed > 0 {
+			continue
+		}
+		if max(torrent.ActivityTime, torrent.Atime) <= threshold {
+			stalled = append(stalled, torrent)
+		}
+	}
+	return stalled
+}
+
+// Return torrents which add time (Atime, unix timestamp seconds) is in [after, before]. 0 bound means unbounded.
+// Torrents with unknown add time (Atime == 0) never match a bounded query.
+func FilterByAddedTime(torrents []*Torrent, after, before int64) []*Torrent {
+	var result []*Torrent
+	for _, torrent := range torrents {
+		if (after > 0 || before > 0) && torrent.Atime == 0 {
+			continue
+		}
+		if (after <= 0 || torrent.Atime >= after) && (before <= 0 || torrent.Atime <= before) {
+			result = append(result, torrent)
+		}
+	}
+	return result
+}
+
+// Return torrents that have all (matchAll is true) or any (matchAll is false) of the tags.
+// Tag comparison is exact (case-sensitive, unlike Torrent.HasTag). Empty tags matches all torrents.
+func FilterByTags(torrents []*Torrent, tags []string, matchAll bool) []*Torrent {
+	if len(tags) == 0 {
+		return torrents
+	}
+	var result []*Torrent
+	for _, torrent := range torrents {
+		var match bool
+		if matchAll {
+			match = !slices.ContainsFunc(tags, func(tag string) bool { return !slices.Contains(torrent.Tags, tag) })
+		} else {
+			match = slices.ContainsFunc(tags, func(tag string) bool { return slices.Contains(torrent.Tags, tag) })
+		}
+		if match {
+			result = append(result, torrent)
+		}
+	}
+	return result
+}
+
+// Return torrents that do not have the exact (case-sensitive) tag.
+func FindTorrentsMissingTag(torrents []*Torrent, tag string) []*Torrent {
+	var result []*Torrent
+	for _, torrent := range torrents {
+		if !slices.Contains(torrent.Tags, tag) {
+			result = append(result, torrent)
+		}
+	}
+	return result
+}
+
+// Return torrents that do not have any tag with the prefix, e.g. "site:" to find torrents which site
+// can not be attributed.
+func FindTorrentsMissingTagPrefix(torrents []*Torrent, prefix string) []*Torrent {
+	var result []*Torrent
+	for _, torrent := range torrents {
+		if !slices.ContainsFunc(torrent.Tags, func(tag string) bool { return strings.HasPrefix(tag, prefix) }) {
+			result = append(result, torrent)
+		}
+	}
+	return result
+}
+
+// Return torrents which Ratio exceeds the ratio cap of it's site. siteLimits: site => ratio cap, site is parsed
+// from "site:" tag (see Torrent.GetSiteFromTag). The cap under "" key is the default, used for torrents without
+// a site tag, or which site is not in siteLimits. Caps <= 0 are ignored.
+// Torrents with zero Downloaded (e.g. cross-seeded ones) are skipped, as their Ratio is INFINITE_RATIO.
+func FindOverRatioTorrents(torrents []*Torrent, siteLimits map[string]float64) []*Torrent {
+	var result []*Torrent
+	for _, torrent := range torrents {
+		if torrent.Downloaded == 0 {
+			continue
+		}
+		limit, ok := siteLimits[torrent.GetSiteFromTag()]
+		if !ok {
+			limit = siteLimits[""]
+		}
+		if limit > 0 && torrent.Ratio > limit {
+			result = append(result, torrent)
+		}
+	}
+	return result
+}
+
+// Return torrents which tracker is on any of domains, e.g. to find torrents still using an old tracker domain
+// after site migrated it, then update them with EditTorrentTracker (edittracker cmd).
+// A domain can also be a tracker url. Matching is case-insensitive and sub-domains of a domain also match,
+// e.g. "old.com" matches "tracker.old.com".
+func FindTorrentsOnTracker(torrents []*Torrent, domains []string) []*Torrent {
+	var normalizedDomains []string
+	for _, domain := range domains {
+		if util.IsUrl(domain) {
+			domain = util.ParseUrlHostname(domain)
+		}
+		if domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), "."); domain != "" {
+			normalizedDomains = append(normalizedDomains, domain)
+		}
+	}
+	var result []*Torrent
+	for _, torrent := range torrents {
+		trackerDomain := strings.ToLower(torrent.TrackerDomain)
+		if trackerDomain == "" {
+			continue
+		}
+		if slices.ContainsFunc(normalizedDomains, func(domain string) bool {
+			return trackerDomain == domain || strings.HasSuffix(trackerDomain, "."+domain)
+		}) {
+			result = append(result, torrent)
+		}
+	}
+	return result
+}
+
+// Return torrents without a tracker (empty TrackerDomain), e.g. DHT-only torrents.
+// Note qb reports only the current working tracker of torrent, so torrents which trackers are all not working
+// are also returned. Use VerifyTrackerless to exclude them.
+// To report / delete these torrents from command line, use "--tracker none" flag of show / delete cmds.
+func FindTrackerless(torrents []*Torrent) []*Torrent {
+	var result []*Torrent
+	for _, torrent := range torrents {
+		if torrent.TrackerDomain == "" {
+			result = append(result, torrent)
+		}
+	}
+	return result
+}
+
+// Return torrents that genuinely do NOT have any tracker (DHT / PeX / LSD pseudo trackers excluded) in client.
+func VerifyTrackerless(clientInstance Client, torrents []*Torrent) ([]*Torrent, error) {
+	var result []*Torrent
+	for _, torrent := range torrents {
+		trackers, err := clientInstance.GetTorrentTrackers(torrent.InfoHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get torrent %s trackers: %w", torrent.InfoHash, err)
+		}
+		if len(trackers) == 0 {
+			result = append(result, torrent)
+		}
+	}
+	return result, nil
+}
+
+// Return torrents which name contains meta ("__meta." suffix, see GenerateNameWithMeta) that is not parsed,
+// e.g. leaked into a client that does not store meta in name (transmission), or by an external tool.
+// qb torrents normally never match, as their names are already parsed when fetched.
+func FindTorrentsWithMetaInName(torrents []*Torrent) []*Torrent {
+	var result []*Torrent
+	for _, torrent := range torrents {
+		if _, meta := ParseMetaFromName(torrent.Name); len(meta) > 0 {
+			result = append(result, torrent)
+		}
+	}
+	return result
+}
+
+// Rename torrents to strip the meta suffix from their names. Return the number of renamed torrents.
+// Use it with FindTorrentsWithMetaInName.
+func StripMetaFromTorrentNames(clientInstance Client, torrents []*Torrent) (renamed int, err error) {
+	for _, torrent := range torrents {
+		name, meta := ParseMetaFromName(torrent.Name)
+		if len(meta) == 0 || name == "" {
+			continue
+		}
+		if err = clientInstance.ModifyTorrent(torrent.InfoHash, &TorrentOption{Name: name}, nil); err != nil {
+			return renamed, fmt.Errorf("failed to rename torrent %s: %w", torrent.InfoHash, err)
+		}
+		renamed++
+	}
+	return renamed, nil
+}
+
+// Conditions to select torrents. All non-empty conditions must match. A nil filter matches all torrents.
+type TorrentFilter struct {
+	StateFilter string           // see Torrent.MatchStateFilter. E.g. "_active", "seeding"
+	Category    string           // "none" matches uncategorized torrents
+	Tag         string           // torrent must have this tag
+	Tracker     string           // see Torrent.MatchTracker
+	Filter      string           // name must contain it (case-insensitive). See Torrent.MatchFilter
+	MinSize     int64            // if > 0, torrent size must >= it
+	MaxSize     int64            // if > 0, torrent size must <= it
+	Meta        []*MetaCondition // all must match
+}
+
+func (f *TorrentFilter) Matches(torrent *Torrent) bool {
+	if f == nil {
+		return true
+	}
+	if f.StateFilter != "" && !torrent.MatchStateFilter(f.StateFilter) {
+		return false
+	}
+	if f.Category != "" {
+		if f.Category == constants.NONE {
+			if torrent.Category != "" {
+				return false
+			}
+		} else if torrent.Category != f.Category {
+			return false
+		}
+	}
+	for _, condition := range f.Meta {
+		if !condition.Matches(torrent) {
+			return false
+		}
+	}
+	return (f.Tag == "" || torrent.HasTag(f.Tag)) &&
+		(f.Tracker == "" || torrent.MatchTracker(f.Tracker)) &&
+		torrent.MatchFilter(f.Filter) &&
+		(f.MinSize <= 0 || torrent.Size >= f.MinSize) &&
+		(f.MaxSize <= 0 || torrent.Size <= f.MaxSize)
+}
+
+// Comparison operators of MetaCondition, longer ones first.
+var metaConditionOps = []string{"<=", ">=", "!=", "==", "<", ">", "="}
+
+// A numeric comparison against a value of Torrent.Meta, e.g. "addtime<1700000000".
+type MetaCondition struct {
+	Key   string
+	Op    string // one of "<", "<=", ">", ">=", "=", "!="
+	Value int64
+}
+
+// Parse a "[meta.]<key><op><value>" expression, e.g. "meta.addtime<1700000000". See MetaCondition.
+func ParseMetaCondition(expr string) (*MetaCondition, error) {
+	expr = strings.TrimPrefix(strings.TrimSpace(expr), "meta.")
+	for _, op := range metaConditionOps {
+		key, valueStr, found := strings.Cut(expr, op)
+		if !found {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("invalid meta condition %q: empty key", expr)
+		}
+		value, err := strconv.ParseInt(strings.TrimSpace(valueStr), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid meta condition %q: %w", expr, err)
+		}
+		if op == "==" {
+			op = "="
+		}
+		return &MetaCondition{Key: key, Op: op, Value: value}, nil
+	}
+	return nil, fmt.Errorf("invalid meta condition %q: no comparison operator", expr)
+}
+
+// Torrents which do not have the meta key only match a "!=" condition.
+func (condition *MetaCondition) Matches(torrent *Torrent) bool {
+	value, ok := torrent.Meta[condition.Key]
+	if !ok {
+		return condition.Op == "!="
+	}
+	switch condition.Op {
+	case "<":
+		return value < condition.Value
+	case "<=":
+		return value <= condition.Value
+	case ">":
+		return value > condition.Value
+	case ">=":
+		return value >= condition.Value
+	case "=":
+		return value == condition.Value
+	case "!=":
+		return value != condition.Value
+	}
+	return false
+}
+
+// Return torrents that match filter.
+func FilterTorrents(torrents []*Torrent, f *TorrentFilter) []*Torrent {
+	var result []*Torrent
+	for _, torrent := range torrents {
+		if f.Matches(torrent) {
+			result = append(result, torrent)
+		}
+	}
+	return result
+}
+
+// Return the TorrentFilter of the named query in config file ("queries").
+// If no such query, the error lists all available query names.
+func GetSavedQueryFilter(name string) (*TorrentFilter, error) {
+	query := config.GetQueryConfig(name)
+	if query == nil {
+		names := []string{}
+		for _, query := range config.Get().Queries {
+			names = append(names, query.Name)
+		}
+		return nil, fmt.Errorf("query %q not found. Available queries: %s", name, strings.Join(names, ", "))
+	}
+	filter := &TorrentFilter{
+		StateFilter: query.StateFilter,
+		Category:    query.Category,
+		Tag:         query.Tag,
+		Tracker:     query.Tracker,
+		Filter:      query.Filter,
+	}
+	for _, expr := range query.Meta {
+		condition, err := ParseMetaCondition(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid meta of query %s: %w", name, err)
+		}
+		filter.Meta = append(filter.Meta, condition)
+	}
+	var err error
+	if query.MinSize != "" {
+		if filter.MinSize, err = util.RAMInBytes(query.MinSize); err != nil {
+			return nil, fmt.Errorf("invalid minSize of query %s: %w", name, err)
+		}
+	}
+	if query.MaxSize != "" {
+		if filter.MaxSize, err = util.RAMInBytes(query.MaxSize); err != nil {
+			return nil, fmt.Errorf("invalid maxSize of query %s: %w", name, err)
+		}
+	}
+	return filter, nil
+}
+
+// Return torrents of client that match the named query in config file. See GetSavedQueryFilter.
+func RunSavedQuery(clientInstance Client, name string) ([]*Torrent, error) {
+	filter, err := GetSavedQueryFilter(name)
+	if err != nil {
+		return nil, err
+	}
+	torrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get torrents: %w", err)
+	}
+	return FilterTorrents(torrents, filter), nil
+}
+
+// Return up to n torrents randomly chosen using a shuffle seeded by seed, e.g. to dry-run a policy on a
+// representative sample of a huge library. Same input and seed always produce the same sample.
+// Return all torrents (shuffled) if n >= len(torrents). The input slice is not modified.
+func SampleTorrents(torrents []*Torrent, n int, seed int64) []*Torrent {
+	sample := slices.Clone(torrents)
+	random := rand.New(rand.NewSource(seed))
+	random.Shuffle(len(sample), func(i, j int) { sample[i], sample[j] = sample[j], sample[i] })
+	if n < len(sample) {
+		sample = sample[:max(n, 0)]
+	}
+	return sample
+}
+
+// Group torrents that have the same contents (see Torrent.ContentID) but different info-hashes, e.g. the same
+// contents added twice from repacked .torrent files. Return content id => torrents, only groups of 2+ torrents.
+// Cross-seed torrents of different trackers legitimately share contents and appear here too, so it's advisory.
+// Only torrents with same SizeTotal can be duplicates, so contents are fetched from client only for those.
+func FindDuplicateContent(clientInstance Client, torrents []*Torrent) (map[string][]*Torrent, error) {
+	torrentsBySize := map[int64][]*Torrent{}
+	for _, torrent := range torrents {
+		torrentsBySize[torrent.SizeTotal] = append(torrentsBySize[torrent.SizeTotal], torrent)
+	}
+	torrentsByContent := map[string][]*Torrent{}
+	for _, sameSizeTorrents := range torrentsBySize {
+		if len(sameSizeTorrents) < 2 {
+			continue
+		}
+		for _, torrent := range sameSizeTorrents {
+			contentId, err := torrent.ContentID(clientInstance)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get content id of torrent %s: %w", torrent.InfoHash, err)
+			}
+			torrentsByContent[contentId] = append(torrentsByContent[contentId], torrent)
+		}
+	}
+	for contentId, sameContentTorrents := range torrentsByContent {
+		if len(sameContentTorrents) < 2 {
+			delete(torrentsByContent, contentId)
+		}
+	}
+	return torrentsByContent, nil
+}
@@ -0,0 +1,56 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFilterByTags(t *testing.T) {
+	torrents := []*Torrent{
+		{InfoHash: "a", Tags: []string{"foo", "bar"}},
+		{InfoHash: "b", Tags: []string{"foo"}},
+		{InfoHash: "c", Tags: []string{"Foo"}},
+		{InfoHash: "d"},
+	}
+	tests := []struct {
+		tags     []string
+		matchAll bool
+		want     []string
+	}{
+		{nil, true, []string{"a", "b", "c", "d"}},
+		{[]string{}, false, []string{"a", "b", "c", "d"}},
+		{[]string{"foo"}, true, []string{"a", "b"}},
+		{[]string{"foo", "bar"}, true, []string{"a"}},
+		{[]string{"foo", "bar"}, false, []string{"a", "b"}},
+		{[]string{"bar", "Foo"}, false, []string{"a", "c"}},
+		{[]string{"baz"}, false, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, torrent := range FilterByTags(torrents, tt.tags, tt.matchAll) {
+			got = append(got, torrent.InfoHash)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FilterByTags(%v, %t) = %v, want %v", tt.tags, tt.matchAll, got, tt.want)
+		}
+	}
+}
+
+func TestFindOverRatioTorrents(t *testing.T) {
+	torrents := []*Torrent{
+		{InfoHash: "a", Downloaded: 100, Ratio: 3, Tags: []string{"site:foo"}},
+		{InfoHash: "b", Downloaded: 100, Ratio: 3},
+		{InfoHash: "c", Downloaded: 100, Ratio: 1.5},
+		{InfoHash: "d", Downloaded: 0, Ratio: INFINITE_RATIO}, // xseed
+		{InfoHash: "e", Downloaded: 100, Ratio: 1.5, Tags: []string{"site:bar"}},
+	}
+	siteLimits := map[string]float64{"": 2, "foo": 5, "bar": 1}
+	var got []string
+	for _, torrent := range FindOverRatioTorrents(torrents, siteLimits) {
+		got = append(got, torrent.InfoHash)
+	}
+	want := []string{"b", "e"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindOverRatioTorrents() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,1241 @@
+package client
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/anacrolix/torrent/metainfo"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/config"
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+const (
+	// Interval of polling client when waiting for async operations (e.g. moving) to finish.
+	POLL_INTERVAL = time.Second
+	// Max attempts of adding a torrent in AddTorrentIdempotent.
+	ADD_TORRENT_MAX_ATTEMPTS = 3
+	// Max time to wait for torrents to be relocated in AssignCategoryWithRelocation.
+	RELOCATION_TIMEOUT = time.Minute
+	// Meta key of the unix timestamp (seconds) after which a torrent added by AddTorrentDelayed should be resumed.
+	META_KEY_RESUME_AFTER = "resumeat"
+	// Min fraction of checking torrents for IsStartupChecking to report client is in startup checking window.
+	STARTUP_CHECKING_RATIO = 0.5
+)
+
+// Orders of CleanupPolicy.
+const (
+	CLEANUP_ORDER_OLDEST = "oldest" // remove the earliest added torrents first
+	CLEANUP_ORDER_RATIO  = "ratio"  // remove the lowest ratio torrents first
+)
+
+// Move torrents to newPath, then poll client until each torrent's SavePath reflects the new path
+// or timeout elapses. Client moves are async, and the target may be briefly unavailable,
+// so torrents that failed to move are reported in the returned error.
+// Return the number of torrents that are verified to be in the new path.
+func MoveTorrentsVerified(clientInstance Client, infoHashes []string, newPath string,
+	timeout time.Duration) (moved int, err error) {
+	if len(infoHashes) == 0 {
+		return 0, nil
+	}
+	if err = clientInstance.SetTorrentsSavePath(infoHashes, newPath); err != nil {
+		return 0, fmt.Errorf("failed to set torrents save path: %w", err)
+	}
+	pending := slices.Clone(infoHashes)
+	deadline := time.Now().Add(timeout)
+	for {
+		clientInstance.PurgeCache()
+		pending, err = filterTorrentsNotInPath(clientInstance, pending, newPath)
+		if err != nil {
+			return len(infoHashes) - len(pending), err
+		}
+		if len(pending) == 0 || !time.Now().Before(deadline) {
+			break
+		}
+		log.Tracef("Waiting for %d torrents to be moved to %q", len(pending), newPath)
+		time.Sleep(POLL_INTERVAL)
+	}
+	moved = len(infoHashes) - len(pending)
+	if len(pending) > 0 {
+		return moved, fmt.Errorf("%d torrents are not moved to %q in time: %s",
+			len(pending), newPath, strings.Join(pending, ", "))
+	}
+	return moved, nil
+}
+
+// Return infoHashes of torrents that does NOT exist or whose save path is not savePath.
+func filterTorrentsNotInPath(clientInstance Client, infoHashes []string, savePath string) ([]string, error) {
+	var notInPath []string
+	for _, infoHash := range infoHashes {
+		torrent, err := clientInstance.GetTorrent(infoHash)
+		if err != nil {
+			return nil, err
+		}
+		if torrent == nil || !IsSamePath(torrent.SavePath, savePath) {
+			notInPath = append(notInPath, infoHash)
+		}
+	}
+	return notInPath, nil
+}
+
+// Check whether two client paths are same, ignoring trailing path separator and the style of separator.
+func IsSamePath(path1 string, path2 string) bool {
+	normalize := func(path string) string {
+		return strings.TrimSuffix(strings.ReplaceAll(path, `\`, "/"), "/")
+	}
+	return normalize(path1) == normalize(path2)
+}
+
+// Return the (v1) info-hash of torrent contents, which could be a .torrent file contents or a magnet url.
+func GetTorrentContentInfoHash(torrentContent []byte) (string, error) {
+	if util.IsTorrentUrl(string(torrentContent)) {
+		if !strings.HasPrefix(string(torrentContent), "magnet:") {
+			return "", fmt.Errorf("can not get info-hash of http torrent url")
+		}
+		magnet, err := metainfo.ParseMagnetUri(string(torrentContent))
+		if err != nil {
+			return "", fmt.Errorf("failed to parse magnet url: %w", err)
+		}
+		return magnet.InfoHash.HexString(), nil
+	}
+	metaInfo, err := metainfo.Load(bytes.NewReader(torrentContent))
+	if err != nil {
+		return "", fmt.Errorf("failed to parse torrent: %w", err)
+	}
+	return metaInfo.HashInfoBytes().HexString(), nil
+}
+
+// Add a torrent to client, safe to be retried. The info-hash of torrent is computed in ahead,
+// if torrent already exists in client, it's not added again and alreadyExisted is true.
+// If add request fails with a network error (e.g. timeout), whether the torrent was actually added is unknown,
+// so check whether it now exists in client before retrying.
+func AddTorrentIdempotent(clientInstance Client, torrentContent []byte, option *TorrentOption,
+	meta map[string]int64) (infoHash string, alreadyExisted bool, err error) {
+	infoHash, err = GetTorrentContentInfoHash(torrentContent)
+	if err != nil {
+		return "", false, err
+	}
+	if torrent, err := clientInstance.GetTorrent(infoHash); err != nil {
+		return infoHash, false, err
+	} else if torrent != nil {
+		return infoHash, true, nil
+	}
+	for i := 0; i < ADD_TORRENT_MAX_ATTEMPTS; i++ {
+		err = clientInstance.AddTorrent(torrentContent, option, meta)
+		if err == nil || !util.AsNetworkError(err) {
+			return infoHash, false, err
+		}
+		log.Debugf("Add torrent %s network error (attempt %d/%d): %v", infoHash, i+1, ADD_TORRENT_MAX_ATTEMPTS, err)
+		clientInstance.PurgeCache()
+		if torrent, _ := clientInstance.GetTorrent(infoHash); torrent != nil {
+			return infoHash, false, nil
+		}
+	}
+	return infoHash, false, fmt.Errorf("failed to add torrent %s: %w", infoHash, err)
+}
+
+// Add a torrent to client in paused state and schedule it to be resumed after resumeAfter.
+// Useful for staggered starts of a batch of torrents. The resume is done in a background goroutine,
+// which only works within the process lifetime. The intended resume time is also recorded
+// in META_KEY_RESUME_AFTER meta of torrent, so a separate sweeper can act on it if ptool exits early.
+// The passed option and meta are not modified.
+func AddTorrentDelayed(clientInstance Client, torrentContent []byte, option *TorrentOption,
+	meta map[string]int64, resumeAfter time.Duration) (infoHash string, err error) {
+	infoHash, err = GetTorrentContentInfoHash(torrentContent)
+	if err != nil {
+		return "", err
+	}
+	delayedOption := &TorrentOption{}
+	if option != nil {
+		*delayedOption = *option
+	}
+	delayedOption.Pause = true
+	delayedMeta := map[string]int64{}
+	for key, value := range meta {
+		delayedMeta[key] = value
+	}
+	delayedMeta[META_KEY_RESUME_AFTER] = time.Now().Add(resumeAfter).Unix()
+	if err = clientInstance.AddTorrent(torrentContent, delayedOption, delayedMeta); err != nil {
+		return infoHash, err
+	}
+	time.AfterFunc(resumeAfter, func() {
+		clientInstance.PurgeCache()
+		torrent, err := clientInstance.GetTorrent(infoHash)
+		if err != nil || torrent == nil {
+			log.Warnf("Failed to get delayed torrent %s to resume: %v", infoHash, err)
+			return
+		}
+		// Already resumed by ResumeScheduled, the user may have paused it again since.
+		if torrent.Meta[META_KEY_RESUME_AFTER] <= 0 {
+			return
+		}
+		if err := resumeScheduledTorrent(clientInstance, torrent); err != nil {
+			log.Warnf("Failed to resume delayed torrent %s: %v", infoHash, err)
+		}
+	})
+	return infoHash, nil
+}
+
+// Add a torrent to client only if client has enough free disk space for it: FreeSpaceOnDisk >= torrent size
+// + headroom, so that a torrent which can't complete is never added. If free space is unknown, it's added.
+// The bytes yet to be written by torrents being downloaded (UnfinishedDownloadingSize, if known) are subtracted
+// from free space, so that a batch of adds in quick succession does not overcommit the disk.
+// Torrent contents must be a .torrent file (not url), as size of torrent is required.
+// Return added = false (with nil err) if there is not enough space.
+func AddTorrentIfSpace(clientInstance Client, torrentContent []byte, option *TorrentOption,
+	meta map[string]int64, headroom int64) (added bool, err error) {
+	if util.IsTorrentUrl(string(torrentContent)) {
+		return false, fmt.Errorf("can not get size of torrent url")
+	}
+	metaInfo, err := metainfo.Load(bytes.NewReader(torrentContent))
+	if err != nil {
+		return false, fmt.Errorf("failed to parse torrent: %w", err)
+	}
+	info, err := metaInfo.UnmarshalInfo()
+	if err != nil {
+		return false, fmt.Errorf("failed to parse torrent info: %w", err)
+	}
+	status, err := clientInstance.GetStatus()
+	if err != nil {
+		return false, fmt.Errorf("failed to get client status: %w", err)
+	}
+	if freeSpace := status.FreeSpaceOnDisk; freeSpace >= 0 {
+		freeSpace -= max(status.UnfinishedDownloadingSize, 0)
+		if size := info.TotalLength(); freeSpace < size+headroom {
+			log.Debugf("Skip adding torrent %s (%s) to client %s: available free space %s < size + headroom",
+				info.BestName(), util.BytesSize(float64(size)), clientInstance.GetName(),
+				util.BytesSize(float64(freeSpace)))
+			return false, nil
+		}
+	}
+	if err = clientInstance.AddTorrent(torrentContent, option, meta); err != nil {
+		return false, err
+	}
+	// So that next call sees the added torrent in UnfinishedDownloadingSize.
+	clientInstance.PurgeCache()
+	return true, nil
+}
+
+// Resume torrents of client whose META_KEY_RESUME_AFTER meta time is <= now, and clear that meta of them.
+// It's the sweeper of AddTorrentDelayed, which makes staggered starts survive across separate ptool invocations
+// (e.g. run from cron). It's idempotent. Return the number of torrents that are resumed.
+func ResumeScheduled(clientInstance Client, now time.Time) (resumed int, err error) {
+	torrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return 0, err
+	}
+	for _, torrent := range torrents {
+		resumeAt := torrent.Meta[META_KEY_RESUME_AFTER]
+		if resumeAt <= 0 || resumeAt > now.Unix() {
+			continue
+		}
+		if err = resumeScheduledTorrent(clientInstance, torrent); err != nil {
+			return resumed, err
+		}
+		resumed++
+	}
+	return resumed, nil
+}
+
+// Resume a torrent scheduled by AddTorrentDelayed, and clear it's META_KEY_RESUME_AFTER meta,
+// so that it will not be resumed again (e.g. after user pauses it) by later ResumeScheduled runs.
+func resumeScheduledTorrent(clientInstance Client, torrent *Torrent) error {
+	if err := clientInstance.ResumeTorrents([]string{torrent.InfoHash}); err != nil {
+		return fmt.Errorf("failed to resume torrent %s: %w", torrent.InfoHash, err)
+	}
+	meta := map[string]int64{}
+	for key, value := range torrent.Meta {
+		meta[key] = value
+	}
+	meta[META_KEY_RESUME_AFTER] = 0
+	if err := clientInstance.ModifyTorrent(torrent.InfoHash, &TorrentOption{}, meta); err != nil {
+		return fmt.Errorf("failed to clear resume time meta of torrent %s: %w", torrent.InfoHash, err)
+	}
+	return nil
+}
+
+// A rule of CategorizeByRules. All non-empty conditions must match.
+type CategoryRule struct {
+	Name     string // regexp pattern matched against torrent name. E.g. `S\d+E\d+`
+	Tracker  string // tracker domain or url. See Torrent.MatchTracker
+	MinSize  int64  // if > 0, torrent size must >= it
+	MaxSize  int64  // if > 0, torrent size must <= it
+	Category string // the category to assign
+}
+
+// Assign category to uncategorized torrents of client according to rules. The first matched rule wins.
+// Torrents that already have a category are never touched, to avoid fighting manual organization.
+// Return the number of torrents that are categorized.
+func CategorizeByRules(clientInstance Client, rules []*CategoryRule) (categorized int, err error) {
+	nameRegexes := make([]*regexp.Regexp, len(rules))
+	for i, rule := range rules {
+		if rule.Category == "" {
+			return 0, fmt.Errorf("rule %d: category is empty", i)
+		}
+		if rule.Name != "" {
+			if nameRegexes[i], err = regexp.Compile(rule.Name); err != nil {
+				return 0, fmt.Errorf("rule %d: invalid name pattern: %w", i, err)
+			}
+		}
+	}
+	torrents, err := clientInstance.GetTorrents("", constants.NONE, true)
+	if err != nil {
+		return 0, err
+	}
+	categoryInfoHashes := map[string][]string{}
+	for _, torrent := range torrents {
+		for i, rule := range rules {
+			if (nameRegexes[i] != nil && !nameRegexes[i].MatchString(torrent.Name)) ||
+				(rule.Tracker != "" && !torrent.MatchTracker(rule.Tracker)) ||
+				(rule.MinSize > 0 && torrent.Size < rule.MinSize) ||
+				(rule.MaxSize > 0 && torrent.Size > rule.MaxSize) {
+				continue
+			}
+			categoryInfoHashes[rule.Category] = append(categoryInfoHashes[rule.Category], torrent.InfoHash)
+			break
+		}
+	}
+	for category, infoHashes := range categoryInfoHashes {
+		if err = clientInstance.SetTorrentsCatetory(infoHashes, category); err != nil {
+			return categorized, fmt.Errorf("failed to set torrents category to %q: %w", category, err)
+		}
+		categorized += len(infoHashes)
+	}
+	return categorized, nil
+}
+
+// Merge alias categories (e.g. "movies" vs "Movies") into canonical category: move all torrents of aliases
+// to canonical category, then delete the aliases. canonical category is created if not exists.
+// For clients that categories are simulated by tags (transmission), aliases disappear once they have no torrents.
+// Return the number of torrents that are moved.
+func MergeCategories(clientInstance Client, canonical string, aliases []string) (moved int, err error) {
+	if canonical == "" || canonical == constants.NONE {
+		return 0, fmt.Errorf("invalid canonical category %q", canonical)
+	}
+	aliases = slices.DeleteFunc(slices.Clone(aliases), func(alias string) bool {
+		return alias == "" || alias == constants.NONE || alias == canonical
+	})
+	if len(aliases) == 0 {
+		return 0, nil
+	}
+	categories, err := clientInstance.GetCategories()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get categories: %w", err)
+	}
+	if !slices.ContainsFunc(categories, func(category *TorrentCategory) bool { return category.Name == canonical }) {
+		if err = clientInstance.MakeCategory(canonical, ""); err != nil && !errors.Is(err, ErrUnsupported) {
+			return 0, fmt.Errorf("failed to create category %q: %w", canonical, err)
+		}
+	}
+	for _, alias := range aliases {
+		torrents, err := clientInstance.GetTorrents("", alias, true)
+		if err != nil {
+			return moved, fmt.Errorf("failed to get torrents of category %q: %w", alias, err)
+		}
+		infoHashes := util.Map(torrents, func(torrent *Torrent) string { return torrent.InfoHash })
+		if len(infoHashes) > 0 {
+			if err = clientInstance.SetTorrentsCatetory(infoHashes, canonical); err != nil {
+				return moved, fmt.Errorf("failed to move torrents of category %q: %w", alias, err)
+			}
+			moved += len(infoHashes)
+		}
+	}
+	if err = clientInstance.DeleteCategories(aliases); err != nil && !errors.Is(err, ErrUnsupported) {
+		return moved, fmt.Errorf("failed to delete categories: %w", err)
+	}
+	return moved, nil
+}
+
+// Re-add a torrent (e.g. to rebuild it's metainfo) while preserving it's category, tags, save path, meta,
+// paused state and queue position. The torrent is deleted from client (without files), then re-added
+// using torrentContent. If the info-hash does not change, data is unchanged and hash checking is skipped.
+func ReAddPreservingQueue(clientInstance Client, torrent *Torrent, torrentContent []byte) error {
+	infoHash, err := GetTorrentContentInfoHash(torrentContent)
+	if err != nil {
+		return err
+	}
+	if err = clientInstance.DeleteTorrents([]string{torrent.InfoHash}, false); err != nil {
+		return fmt.Errorf("failed to delete torrent: %w", err)
+	}
+	option := &TorrentOption{
+		Category:     torrent.Category,
+		SavePath:     torrent.SavePath,
+		Tags:         torrent.Tags,
+		Pause:        torrent.State == "paused",
+		SkipChecking: infoHash == torrent.InfoHash,
+	}
+	if err = clientInstance.AddTorrent(torrentContent, option, torrent.Meta); err != nil {
+		return fmt.Errorf("failed to re-add torrent: %w", err)
+	}
+	if torrent.Priority <= 0 {
+		return nil
+	}
+	// Client may take some time to actually add the torrent.
+	for i := 0; ; i++ {
+		clientInstance.PurgeCache()
+		if newTorrent, err := clientInstance.GetTorrent(infoHash); err != nil {
+			return err
+		} else if newTorrent != nil {
+			break
+		}
+		if i >= ADD_TORRENT_MAX_ATTEMPTS {
+			return fmt.Errorf("re-added torrent %s not found in client", infoHash)
+		}
+		time.Sleep(POLL_INTERVAL)
+	}
+	if err = clientInstance.SetTorrentPriority(infoHash, torrent.Priority); err != nil {
+		return fmt.Errorf("failed to restore torrent queue position: %w", err)
+	}
+	return nil
+}
+
+// Pause all running torrents of client, run fn, then resume exactly the torrents that were paused by it.
+// Torrents that were already paused (or completed / errored) before are left untouched.
+// Torrents are resumed even if fn fails. It's useful for applying config changes that only affect new connections.
+func WithTorrentsPaused(clientInstance Client, fn func() error) error {
+	torrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return fmt.Errorf("failed to get torrents: %w", err)
+	}
+	var infoHashes []string
+	for _, torrent := range torrents {
+		if torrent.State != "paused" && torrent.State != "completed" && torrent.State != "error" {
+			infoHashes = append(infoHashes, torrent.InfoHash)
+		}
+	}
+	if len(infoHashes) > 0 {
+		if err = clientInstance.PauseTorrents(infoHashes); err != nil {
+			return fmt.Errorf("failed to pause torrents: %w", err)
+		}
+	}
+	err = fn()
+	if len(infoHashes) > 0 {
+		if resumeErr := clientInstance.ResumeTorrents(infoHashes); resumeErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to resume torrents: %w", resumeErr))
+		}
+	}
+	return err
+}
+
+// Set category of torrents, enable Automatic Torrent Management of them so that client relocates their data
+// to the category's save path, then verify the torrents are actually moved there.
+// It requires the client to support both GetCategorySavePath and SetTorrentsAutoManagement (qb).
+func AssignCategoryWithRelocation(clientInstance Client, infoHashes []string, category string) error {
+	if len(infoHashes) == 0 {
+		return nil
+	}
+	savePath, err := clientInstance.GetCategorySavePath(category)
+	if err != nil {
+		return fmt.Errorf("failed to get category save path: %w", err)
+	}
+	if err = clientInstance.SetTorrentsCatetory(infoHashes, category); err != nil {
+		return fmt.Errorf("failed to set torrents category: %w", err)
+	}
+	if err = clientInstance.SetTorrentsAutoManagement(infoHashes, true); err != nil {
+		return fmt.Errorf("failed to enable auto management: %w", err)
+	}
+	pending := slices.Clone(infoHashes)
+	deadline := time.Now().Add(RELOCATION_TIMEOUT)
+	for {
+		clientInstance.PurgeCache()
+		if pending, err = filterTorrentsNotInPath(clientInstance, pending, savePath); err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("%d torrents are not relocated to %q in time: %s",
+				len(pending), savePath, strings.Join(pending, ", "))
+		}
+		time.Sleep(POLL_INTERVAL)
+	}
+}
+
+// Speed limits (bytes/s) applied to torrents by ApplyTagSpeedPolicies. 0 means do not change, -1 means no limit.
+type SpeedPolicy struct {
+	DownloadSpeedLimit int64
+	UploadSpeedLimit   int64
+}
+
+// Return the more restrictive one of two speed limits (0 means do not change, -1 means no limit).
+func moreRestrictiveSpeedLimit(limit1, limit2 int64) int64 {
+	if limit1 > 0 && limit2 > 0 {
+		return min(limit1, limit2)
+	}
+	if limit1 > 0 || limit2 > 0 {
+		return max(limit1, limit2)
+	}
+	return min(limit1, limit2)
+}
+
+// Apply speed limits to torrents according to their tags. policies: tag => speed policy.
+// If a torrent has multiple tags with policies, the most restrictive limits are applied.
+// Torrents which speed limits are already the target ones are skipped.
+// Return the number of torrents which speed limits are changed.
+func ApplyTagSpeedPolicies(clientInstance Client, policies map[string]*SpeedPolicy) (applied int, err error) {
+	if len(policies) == 0 {
+		return 0, nil
+	}
+	torrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return 0, err
+	}
+	policyInfoHashes := map[SpeedPolicy][]string{}
+	for _, torrent := range torrents {
+		var target *SpeedPolicy
+		for _, tag := range torrent.Tags {
+			policy := policies[tag]
+			if policy == nil {
+				continue
+			}
+			if target == nil {
+				target = &SpeedPolicy{}
+				*target = *policy
+			} else {
+				target.DownloadSpeedLimit = moreRestrictiveSpeedLimit(target.DownloadSpeedLimit, policy.DownloadSpeedLimit)
+				target.UploadSpeedLimit = moreRestrictiveSpeedLimit(target.UploadSpeedLimit, policy.UploadSpeedLimit)
+			}
+		}
+		if target == nil {
+			continue
+		}
+		// Torrent speed limits are normalized: -1 means no limit.
+		if target.DownloadSpeedLimit == torrent.DownloadSpeedLimit {
+			target.DownloadSpeedLimit = 0
+		}
+		if target.UploadSpeedLimit == torrent.UploadSpeedLimit {
+			target.UploadSpeedLimit = 0
+		}
+		if target.DownloadSpeedLimit == 0 && target.UploadSpeedLimit == 0 {
+			continue
+		}
+		policyInfoHashes[*target] = append(policyInfoHashes[*target], torrent.InfoHash)
+	}
+	for policy, infoHashes := range policyInfoHashes {
+		err = clientInstance.SetTorrentsSpeedLimit(infoHashes, policy.DownloadSpeedLimit, policy.UploadSpeedLimit)
+		if err != nil {
+			return applied, fmt.Errorf("failed to set torrents speed limit: %w", err)
+		}
+		applied += len(infoHashes)
+	}
+	return applied, nil
+}
+
+// Return true if client is (most likely) in the startup window, e.g. qb checking resume data of all torrents
+// right after restart, when at least STARTUP_CHECKING_RATIO of torrents are in "checking" state.
+// Speeds are all zero in that window, so stall / health checks should be skipped.
+func IsStartupChecking(clientInstance Client) (bool, error) {
+	torrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return false, fmt.Errorf("failed to get torrents: %w", err)
+	}
+	if len(torrents) == 0 {
+		return false, nil
+	}
+	checking := 0
+	for _, torrent := range torrents {
+		if torrent.State == "checking" {
+			checking++
+		}
+	}
+	return float64(checking)/float64(len(torrents)) >= STARTUP_CHECKING_RATIO, nil
+}
+
+// Reannounce only downloading torrents that are stalled for at least minStall (see FindStalledDownloads),
+// instead of all torrents, to avoid hammering trackers. Return the number of reannounced torrents.
+// Do nothing if client is in startup checking window (see IsStartupChecking).
+func ReannounceStalled(clientInstance Client, minStall time.Duration) (count int, err error) {
+	if startupChecking, err := IsStartupChecking(clientInstance); err != nil {
+		return 0, err
+	} else if startupChecking {
+		log.Debugf("Client %s is checking torrents after startup, skip reannouncing", clientInstance.GetName())
+		return 0, nil
+	}
+	torrents, err := clientInstance.GetTorrents("downloading", "", true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get torrents: %w", err)
+	}
+	stalled := FindStalledDownloads(torrents, minStall)
+	if len(stalled) == 0 {
+		return 0, nil
+	}
+	infoHashes := util.Map(stalled, func(t *Torrent) string { return t.InfoHash })
+	if err = clientInstance.ReannounceTorrents(infoHashes); err != nil {
+		return 0, fmt.Errorf("failed to reannounce torrents: %w", err)
+	}
+	return len(infoHashes), nil
+}
+
+// Parse a "HH:MM" time of day, return minutes after midnight.
+func parseTimeOfDay(str string) (int, error) {
+	t, err := time.Parse("15:04", str)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q: %w", str, err)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Return true if now is in the daily window [start, end), which may cross midnight (e.g. 23:00 - 07:00).
+// start and end are minutes after midnight. Window of equal start and end is empty.
+func inDailyWindow(start, end int, now time.Time) bool {
+	minutes := now.Hour()*60 + now.Minute()
+	if start <= end {
+		return minutes >= start && minutes < end
+	}
+	return minutes >= start || minutes < end
+}
+
+// Apply global download / upload speed limits (bytes/s, <= 0 means no limit) if now is within quiet hours
+// [start, end) (in "HH:MM" format, the window may cross midnight), otherwise restore unlimited global speed.
+// Limits that are already in effect are not set again, so it's safe to run it periodically (e.g. by cron).
+func ApplyQuietHours(clientInstance Client, start, end string, downLimit, upLimit int64, now time.Time) error {
+	startMinutes, err := parseTimeOfDay(start)
+	if err != nil {
+		return err
+	}
+	endMinutes, err := parseTimeOfDay(end)
+	if err != nil {
+		return err
+	}
+	if !inDailyWindow(startMinutes, endMinutes, now) {
+		downLimit, upLimit = 0, 0
+	}
+	return SetGlobalSpeedLimits(clientInstance, downLimit, upLimit)
+}
+
+// Set global download / upload speed limits (bytes/s, <= 0 means no limit) of client.
+// Limits that are already equal to target values are not touched.
+func SetGlobalSpeedLimits(clientInstance Client, downLimit, upLimit int64) error {
+	status, err := clientInstance.GetStatus()
+	if err != nil {
+		return fmt.Errorf("failed to get client status: %w", err)
+	}
+	limits := []struct {
+		variable string
+		current  int64
+		target   int64
+	}{
+		{"global_download_speed_limit", status.DownloadSpeedLimit, max(downLimit, 0)},
+		{"global_upload_speed_limit", status.UploadSpeedLimit, max(upLimit, 0)},
+	}
+	for _, limit := range limits {
+		if max(limit.current, 0) == limit.target {
+			continue
+		}
+		if err := clientInstance.SetConfig(limit.variable, fmt.Sprint(limit.target)); err != nil {
+			return fmt.Errorf("failed to set %s: %w", limit.variable, err)
+		}
+	}
+	return nil
+}
+
+// Apply the named speed profile in config file ("speedProfiles") to client, setting it's global speed limits.
+// If no such profile, the error lists all available profile names.
+func ApplySpeedProfile(clientInstance Client, profileName string) error {
+	profile := config.GetSpeedProfileConfig(profileName)
+	if profile == nil {
+		names := util.Map(config.Get().SpeedProfiles, func(p *config.SpeedProfileConfigStruct) string { return p.Name })
+		return fmt.Errorf("speed profile %q not found. Available profiles: %s", profileName, strings.Join(names, ", "))
+	}
+	var limits [2]int64
+	for i, limitStr := range []string{profile.DownloadSpeedLimit, profile.UploadSpeedLimit} {
+		if limitStr == "" {
+			continue
+		}
+		limit, err := util.RAMInBytes(limitStr)
+		if err != nil {
+			return fmt.Errorf("invalid speed limit %q of speed profile %s: %w", limitStr, profileName, err)
+		}
+		limits[i] = limit
+	}
+	return SetGlobalSpeedLimits(clientInstance, limits[0], limits[1])
+}
+
+// Policy to select torrents to remove by PlanCleanup / EnforceMaxTorrents.
+type CleanupPolicy struct {
+	Order       string   // CLEANUP_ORDER_*, default is CLEANUP_ORDER_OLDEST
+	PinnedTags  []string // torrents with any of these tags are never removed
+	DeleteFiles bool     // delete files of removed torrents, unless they are used by other xseed torrents
+	// Only remove torrents added by ptool (see Torrent.IsPtoolManaged), leaving manually added torrents untouched.
+	PtoolManagedOnly bool
+}
+
+// Return at most count torrents that should be removed first according to the policy.
+// Pinned and moving torrents, and not ptool managed torrents if policy.PtoolManagedOnly is set, are excluded.
+func PlanCleanup(torrents []*Torrent, policy *CleanupPolicy, count int) []*Torrent {
+	var candidates []*Torrent
+	for _, torrent := range torrents {
+		if !torrent.IsMoving() && !slices.ContainsFunc(policy.PinnedTags, torrent.HasTag) &&
+			(!policy.PtoolManagedOnly || torrent.IsPtoolManaged()) {
+			candidates = append(candidates, torrent)
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b *Torrent) int {
+		if policy.Order == CLEANUP_ORDER_RATIO {
+			if c := cmp.Compare(a.Ratio, b.Ratio); c != 0 {
+				return c
+			}
+		}
+		return cmp.Compare(a.Atime, b.Atime)
+	})
+	if len(candidates) > count {
+		candidates = candidates[:max(count, 0)]
+	}
+	return candidates
+}
+
+// Strategies of RebalanceCategories.
+const (
+	REBALANCE_EVEN_COUNT = "even-count" // make categories have (about) the same number of torrents
+	REBALANCE_EVEN_SIZE  = "even-size"  // make categories have (about) the same total size of torrents
+)
+
+// Plan moving torrents among categories so they are balanced by strategy (REBALANCE_*).
+// Only torrents of these categories are considered; pinned (having any of pinnedTags) and moving torrents
+// count toward balance but are never moved. Return info-hash => new category of torrents to move.
+func PlanRebalanceCategories(torrents []*Torrent, categories []string, strategy string,
+	pinnedTags []string) (map[string]string, error) {
+	if strategy != REBALANCE_EVEN_COUNT && strategy != REBALANCE_EVEN_SIZE {
+		return nil, fmt.Errorf("invalid strategy %q", strategy)
+	}
+	if len(categories) < 2 {
+		return nil, nil
+	}
+	weight := func(torrent *Torrent) int64 {
+		if strategy == REBALANCE_EVEN_SIZE {
+			return torrent.Size
+		}
+		return 1
+	}
+	loads := map[string]int64{}
+	movables := map[string][]*Torrent{}
+	for _, category := range categories {
+		loads[category] = 0
+	}
+	for _, torrent := range torrents {
+		if _, ok := loads[torrent.Category]; !ok {
+			continue
+		}
+		loads[torrent.Category] += weight(torrent)
+		if !torrent.IsMoving() && !slices.ContainsFunc(pinnedTags, torrent.HasTag) {
+			movables[torrent.Category] = append(movables[torrent.Category], torrent)
+		}
+	}
+	moves := map[string]string{}
+	// Each move strictly reduces the imbalance, so the loop always ends.
+	for {
+		fullest, emptiest := categories[0], categories[0]
+		for _, category := range categories {
+			if loads[category] > loads[fullest] {
+				fullest = category
+			}
+			if loads[category] < loads[emptiest] {
+				emptiest = category
+			}
+		}
+		gap := loads[fullest] - loads[emptiest]
+		// Move the torrent which weight is closest to half of the gap. It must be < gap to reduce the imbalance.
+		distance := func(torrent *Torrent) int64 {
+			d := gap - 2*weight(torrent)
+			return max(d, -d)
+		}
+		index := -1
+		for i, torrent := range movables[fullest] {
+			if w := weight(torrent); w > 0 && w < gap &&
+				(index == -1 || distance(torrent) < distance(movables[fullest][index])) {
+				index = i
+			}
+		}
+		if index == -1 {
+			break
+		}
+		torrent := movables[fullest][index]
+		movables[fullest] = slices.Delete(movables[fullest], index, index+1)
+		movables[emptiest] = append(movables[emptiest], torrent)
+		loads[fullest] -= weight(torrent)
+		loads[emptiest] += weight(torrent)
+		if emptiest == torrent.Category {
+			delete(moves, torrent.InfoHash)
+		} else {
+			moves[torrent.InfoHash] = emptiest
+		}
+	}
+	return moves, nil
+}
+
+// Redistribute torrents among categories by strategy, e.g. for categories mapped to different disks.
+// See PlanRebalanceCategories. Return the number of moved torrents.
+func RebalanceCategories(clientInstance Client, categories []string, strategy string,
+	pinnedTags []string) (moved int, err error) {
+	torrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get torrents: %w", err)
+	}
+	moves, err := PlanRebalanceCategories(torrents, categories, strategy, pinnedTags)
+	if err != nil {
+		return 0, err
+	}
+	infoHashesByCategory := map[string][]string{}
+	for _, infoHash := range util.MapKeys(moves) {
+		infoHashesByCategory[moves[infoHash]] = append(infoHashesByCategory[moves[infoHash]], infoHash)
+	}
+	for _, category := range util.MapKeys(infoHashesByCategory) {
+		if err = clientInstance.SetTorrentsCatetory(infoHashesByCategory[category], category); err != nil {
+			return moved, fmt.Errorf("failed to set category of torrents to %s: %w", category, err)
+		}
+		moved += len(infoHashesByCategory[category])
+	}
+	return moved, nil
+}
+
+// If client has more than maxTorrents torrents, remove torrents selected by PlanCleanup until back under the cap.
+// Return the number of removed torrents. It may remove less than needed if too many torrents are pinned.
+func EnforceMaxTorrents(clientInstance Client, maxTorrents int, policy *CleanupPolicy) (removed int, err error) {
+	torrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get torrents: %w", err)
+	}
+	if len(torrents) <= maxTorrents {
+		return 0, nil
+	}
+	removing := PlanCleanup(torrents, policy, len(torrents)-maxTorrents)
+	if len(removing) == 0 {
+		return 0, nil
+	}
+	infoHashes := util.Map(removing, func(t *Torrent) string { return t.InfoHash })
+	if policy.DeleteFiles {
+		err = DeleteTorrentsAuto(clientInstance, infoHashes)
+	} else {
+		err = clientInstance.DeleteTorrents(infoHashes, false)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete torrents: %w", err)
+	}
+	return len(infoHashes), nil
+}
+
+// Set an upload speed limit (bytes/s, -1 means no limit) that should only apply while torrents are seeding,
+// so that uploads reciprocal to active downloads are not throttled.
+// Neither qb nor transmission has a seeding-only per-torrent upload limit, so it falls back to a plain
+// per-torrent upload limit which is applied only to torrents that are already complete; incomplete torrents
+// are skipped. Run it again (e.g. periodically) to cover torrents that complete later.
+func SetSeedingUploadLimit(clientInstance Client, infoHashes []string, limit int64) error {
+	var seedingInfoHashes []string
+	for _, infoHash := range infoHashes {
+		torrent, err := clientInstance.GetTorrent(infoHash)
+		if err != nil {
+			return fmt.Errorf("failed to get torrent %s: %w", infoHash, err)
+		}
+		if torrent != nil && torrent.IsComplete() {
+			seedingInfoHashes = append(seedingInfoHashes, infoHash)
+		}
+	}
+	return clientInstance.SetTorrentsSpeedLimit(seedingInfoHashes, 0, limit)
+}
+
+// Return true if a newly added torrent has actually started: it's not paused / errored, and it's complete
+// or has any progress, speed or peers.
+func torrentStarted(torrent *Torrent) bool {
+	switch torrent.State {
+	case "paused", "error", "checking":
+		return false
+	case "seeding", "completed":
+		return true
+	}
+	return torrent.SizeCompleted > 0 || torrent.DownloadSpeed > 0 || torrent.Seeders > 0 || torrent.Leechers > 0
+}
+
+// Poll client until all newly added torrents have actually started (have progress, speed or peers), or
+// until within is elapsed. Return the info-hashes of torrents that are still stuck (paused, errored, stalled without
+// any peer, or not found in client), which can be retried or removed.
+func VerifyAddsStarted(clientInstance Client, infoHashes []string, within time.Duration) (
+	stuck []string, err error) {
+	stuck = slices.Clone(infoHashes)
+	deadline := time.Now().Add(within)
+	for {
+		clientInstance.PurgeCache()
+		var pending []string
+		for _, infoHash := range stuck {
+			torrent, err := clientInstance.GetTorrent(infoHash)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get torrent %s: %w", infoHash, err)
+			}
+			if torrent == nil || !torrentStarted(torrent) {
+				pending = append(pending, infoHash)
+			}
+		}
+		stuck = pending
+		if len(stuck) == 0 || !time.Now().Before(deadline) {
+			return stuck, nil
+		}
+		time.Sleep(POLL_INTERVAL)
+	}
+}
+
+// Tag torrents according to their names. patterns: regexp => tag, e.g. `(?i)\b2160p\b` => "4k".
+// Names are matched with meta suffix stripped. Torrents that already have the tag are skipped.
+// Return the number of torrents that are tagged.
+func TagByNameAttributes(clientInstance Client, patterns map[string]string) (tagged int, err error) {
+	type tagPattern struct {
+		regexp *regexp.Regexp
+		tag    string
+	}
+	var tagPatterns []*tagPattern
+	for _, pattern := range util.MapKeys(patterns) {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return 0, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		tagPatterns = append(tagPatterns, &tagPattern{re, patterns[pattern]})
+	}
+	torrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get torrents: %w", err)
+	}
+	tagInfoHashes := map[string][]string{}
+	taggedInfoHashes := map[string]bool{}
+	for _, torrent := range torrents {
+		name, _ := ParseMetaFromName(torrent.Name)
+		for _, tagPattern := range tagPatterns {
+			if torrent.HasTag(tagPattern.tag) || !tagPattern.regexp.MatchString(name) ||
+				slices.Contains(tagInfoHashes[tagPattern.tag], torrent.InfoHash) {
+				continue
+			}
+			tagInfoHashes[tagPattern.tag] = append(tagInfoHashes[tagPattern.tag], torrent.InfoHash)
+			taggedInfoHashes[torrent.InfoHash] = true
+		}
+	}
+	for _, tag := range util.MapKeys(tagInfoHashes) {
+		if err = clientInstance.AddTagsToTorrents(tagInfoHashes[tag], []string{tag}); err != nil {
+			return 0, fmt.Errorf("failed to add tag %q to torrents: %w", tag, err)
+		}
+	}
+	return len(taggedInfoHashes), nil
+}
+
+// Return info-hashes of client torrents of site (which have "site:<site>" tag) that match the filter func.
+func getSiteTorrentInfoHashes(clientInstance Client, site string, filter func(*Torrent) bool) ([]string, error) {
+	torrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get torrents: %w", err)
+	}
+	var infoHashes []string
+	for _, torrent := range torrents {
+		if torrent.GetSiteFromTag() == site && filter(torrent) {
+			infoHashes = append(infoHashes, torrent.InfoHash)
+		}
+	}
+	return infoHashes, nil
+}
+
+// Apply per-torrent speed limits (bytes/s, 0 means do not change, -1 means no limit) to all torrents of site
+// (which have "site:<site>" tag), e.g. during tracker maintenance. Return the number of affected torrents.
+// Use UnthrottleSite to restore.
+func ThrottleSite(clientInstance Client, site string, downLimit, upLimit int64) (affected int, err error) {
+	infoHashes, err := getSiteTorrentInfoHashes(clientInstance, site, func(t *Torrent) bool { return true })
+	if err != nil {
+		return 0, err
+	}
+	if err = clientInstance.SetTorrentsSpeedLimit(infoHashes, downLimit, upLimit); err != nil {
+		return 0, fmt.Errorf("failed to set speed limits: %w", err)
+	}
+	return len(infoHashes), nil
+}
+
+// Remove per-torrent speed limits (set them to -1) of torrents of site. Return the number of affected torrents.
+func UnthrottleSite(clientInstance Client, site string) (affected int, err error) {
+	infoHashes, err := getSiteTorrentInfoHashes(clientInstance, site, (*Torrent).HasCustomSpeedLimit)
+	if err != nil {
+		return 0, err
+	}
+	if err = clientInstance.SetTorrentsSpeedLimit(infoHashes, -1, -1); err != nil {
+		return 0, fmt.Errorf("failed to remove speed limits: %w", err)
+	}
+	return len(infoHashes), nil
+}
+
+// Apply per-torrent speed limits (bytes/s, 0 means do not change, -1 means no limit) to all torrents which
+// Size >= minSize, e.g. to prevent big torrents from hogging bandwidth. Return the number of affected torrents.
+// To restore, call it again with -1 limits.
+func ThrottleLargeTorrents(clientInstance Client, minSize int64, downLimit, upLimit int64) (affected int, err error) {
+	torrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get torrents: %w", err)
+	}
+	var infoHashes []string
+	for _, torrent := range torrents {
+		if torrent.Size >= minSize {
+			infoHashes = append(infoHashes, torrent.InfoHash)
+		}
+	}
+	if len(infoHashes) == 0 {
+		return 0, nil
+	}
+	if err = clientInstance.SetTorrentsSpeedLimit(infoHashes, downLimit, upLimit); err != nil {
+		return 0, fmt.Errorf("failed to set speed limits: %w", err)
+	}
+	return len(infoHashes), nil
+}
+
+// Return torrents of client with their trackers info (including tracker reported seeders / leechers / peers).
+// stateFilter and category are same as of GetTorrents. It's expensive on clients with many torrents,
+// especially on qb, which requires one request per torrent to get trackers.
+func GetTorrentsWithTrackerStats(clientInstance Client, stateFilter, category string) (
+	[]*TorrentWithTrackers, error) {
+	torrents, err := clientInstance.GetTorrents(stateFilter, category, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get torrents: %w", err)
+	}
+	torrentsTrackers, err := clientInstance.GetTorrentsTrackers(
+		util.Map(torrents, func(t *Torrent) string { return t.InfoHash }))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get torrents trackers: %w", err)
+	}
+	result := make([]*TorrentWithTrackers, 0, len(torrents))
+	for _, torrent := range torrents {
+		result = append(result, &TorrentWithTrackers{Torrent: torrent, Trackers: torrentsTrackers[torrent.InfoHash]})
+	}
+	return result, nil
+}
+
+// Check whether two clients store data on the same (e.g. NFS shared) storage, so a torrent added to one
+// can use data managed by the other. It writes a marker file into a's default save path and checks whether
+// it's visible in b's default save path, so both save paths must be accessible from local filesystem,
+// otherwise ErrStorageNotAccessible is returned.
+func ClientsShareStorage(a, b Client) (bool, error) {
+	var savePaths []string
+	for _, clientInstance := range []Client{a, b} {
+		savePath, err := clientInstance.GetConfig("save_path")
+		if err != nil {
+			return false, fmt.Errorf("failed to get client %s save path: %w", clientInstance.GetName(), err)
+		}
+		if savePath == "" {
+			return false, fmt.Errorf("client %s save path is unknown", clientInstance.GetName())
+		}
+		if stat, err := os.Stat(savePath); err != nil || !stat.IsDir() {
+			return false, fmt.Errorf("client %s save path %q: %w", clientInstance.GetName(), savePath,
+				ErrStorageNotAccessible)
+		}
+		savePaths = append(savePaths, savePath)
+	}
+	marker, err := os.CreateTemp(savePaths[0], ".ptool-storage-check-*")
+	if err != nil {
+		return false, fmt.Errorf("failed to create marker file: %w", err)
+	}
+	marker.Close()
+	defer os.Remove(marker.Name())
+	_, err = os.Stat(filepath.Join(savePaths[1], filepath.Base(marker.Name())))
+	return err == nil, nil
+}
+
+// Modifiable options of a torrent at a point of time. See SnapshotTorrentOptions.
+type TorrentOptionsSnapshot struct {
+	InfoHash           string
+	Category           string
+	Tags               []string // substitute (category / meta) tags are not included
+	Paused             bool
+	DownloadSpeedLimit int64 // -1 means no limit
+	UploadSpeedLimit   int64 // -1 means no limit
+}
+
+// Record current category, tags, paused state and speed limits of torrents, which can later be restored by
+// RestoreTorrentOptions. All torrents must exist in client.
+func SnapshotTorrentOptions(clientInstance Client, infoHashes []string) ([]*TorrentOptionsSnapshot, error) {
+	torrents, err := clientInstance.GetTorrents("", "", true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get torrents: %w", err)
+	}
+	torrentsMap := map[string]*Torrent{}
+	for _, torrent := range torrents {
+		torrentsMap[torrent.InfoHash] = torrent
+	}
+	snapshots := make([]*TorrentOptionsSnapshot, 0, len(infoHashes))
+	for _, infoHash := range infoHashes {
+		torrent := torrentsMap[infoHash]
+		if torrent == nil {
+			return nil, fmt.Errorf("torrent %s not found", infoHash)
+		}
+		snapshots = append(snapshots, &TorrentOptionsSnapshot{
+			InfoHash:           torrent.InfoHash,
+			Category:           torrent.Category,
+			Tags:               util.Filter(torrent.Tags, func(tag string) bool { return !IsSubstituteTag(tag) }),
+			Paused:             torrent.State == "paused",
+			DownloadSpeedLimit: torrent.DownloadSpeedLimit,
+			UploadSpeedLimit:   torrent.UploadSpeedLimit,
+		})
+	}
+	return snapshots, nil
+}
+
+// Restore torrents to the options recorded by SnapshotTorrentOptions. Only differences are applied.
+// Torrents that no longer exist in client are skipped. It tries all torrents and returns all errors.
+func RestoreTorrentOptions(clientInstance Client, snapshots []*TorrentOptionsSnapshot) error {
+	clientInstance.PurgeCache()
+	var errs []error
+	for _, snapshot := range snapshots {
+		torrent, err := clientInstance.GetTorrent(snapshot.InfoHash)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get torrent %s: %w", snapshot.InfoHash, err))
+			continue
+		}
+		if torrent == nil {
+			continue
+		}
+		option := &TorrentOption{}
+		if torrent.Category != snapshot.Category {
+			option.Category = cmp.Or(snapshot.Category, constants.NONE)
+		}
+		for _, tag := range snapshot.Tags {
+			if !torrent.HasTag(tag) {
+				option.Tags = append(option.Tags, tag)
+			}
+		}
+		for _, tag := range torrent.Tags {
+			if !IsSubstituteTag(tag) && !slices.Contains(snapshot.Tags, tag) {
+				option.RemoveTags = append(option.RemoveTags, tag)
+			}
+		}
+		paused := torrent.State == "paused"
+		option.Pause = snapshot.Paused && !paused
+		option.Resume = !snapshot.Paused && paused
+		if torrent.DownloadSpeedLimit != snapshot.DownloadSpeedLimit {
+			option.DownloadSpeedLimit = snapshot.DownloadSpeedLimit
+		}
+		if torrent.UploadSpeedLimit != snapshot.UploadSpeedLimit {
+			option.UploadSpeedLimit = snapshot.UploadSpeedLimit
+		}
+		if option.Category == "" && len(option.Tags) == 0 && len(option.RemoveTags) == 0 && !option.Pause &&
+			!option.Resume && option.DownloadSpeedLimit == 0 && option.UploadSpeedLimit == 0 {
+			continue
+		}
+		if err := clientInstance.ModifyTorrent(snapshot.InfoHash, option, nil); err != nil {
+			errs = append(errs, fmt.Errorf("failed to restore torrent %s: %w", snapshot.InfoHash, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Snapshot options of torrents, run fn, and restore the snapshot if fn fails, so that a multi-step bulk
+// modification does not leave torrents half-modified. The returned error contains the error of fn and,
+// if any, the error of restoring.
+func WithRollback(clientInstance Client, infoHashes []string, fn func() error) error {
+	snapshots, err := SnapshotTorrentOptions(clientInstance, infoHashes)
+	if err != nil {
+		return fmt.Errorf("failed to snapshot torrents: %w", err)
+	}
+	if err = fn(); err != nil {
+		if restoreErr := RestoreTorrentOptions(clientInstance, snapshots); restoreErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to rollback: %w", restoreErr))
+		}
+		return err
+	}
+	return nil
+}
+
+// Converge categories of client to desired (category name => save path, "" means client's default):
+// create missing categories and update save paths that differ. If prune is true,
+// also delete categories that are not in desired. It's idempotent.
+func ReconcileCategories(clientInstance Client, desired map[string]string, prune bool) error {
+	categories, err := clientInstance.GetCategories()
+	if err != nil {
+		return fmt.Errorf("failed to get categories: %w", err)
+	}
+	current := map[string]string{}
+	for _, category := range categories {
+		current[category.Name] = category.SavePath
+	}
+	for _, name := range util.MapKeys(desired) {
+		if savePath, ok := current[name]; ok && savePath == desired[name] {
+			continue
+		}
+		log.Debugf("Make category %s with save path %q", name, desired[name])
+		if err = clientInstance.MakeCategory(name, desired[name]); err != nil {
+			return fmt.Errorf("failed to make category %s: %w", name, err)
+		}
+	}
+	if prune {
+		var extraCategories []string
+		for _, name := range util.MapKeys(current) {
+			if _, ok := desired[name]; !ok {
+				extraCategories = append(extraCategories, name)
+			}
+		}
+		if len(extraCategories) > 0 {
+			log.Debugf("Delete categories %v", extraCategories)
+			if err = clientInstance.DeleteCategories(extraCategories); err != nil {
+				return fmt.Errorf("failed to delete categories: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Set tags of torrents (info-hash => tags), in as few client calls as possible by grouping torrents
+// that have identical tags changes. If merge is true, tags are added to torrents' existing tags,
+// otherwise torrents' existing tags that are not in the new tags are also removed.
+// It tries all torrents and returns all errors, each of which contains the affected info-hashes.
+func SetTorrentsTagsMap(clientInstance Client, tagsByHash map[string][]string, merge bool) error {
+	var currentTags map[string][]string
+	if !merge {
+		torrents, err := clientInstance.GetTorrents("", "", true)
+		if err != nil {
+			return fmt.Errorf("failed to get torrents: %w", err)
+		}
+		currentTags = map[string][]string{}
+		for _, torrent := range torrents {
+			currentTags[torrent.InfoHash] = torrent.Tags
+		}
+	}
+	// tags key (sorted comma-separated tags) => info-hashes
+	addGroups := map[string][]string{}
+	removeGroups := map[string][]string{}
+	var errs []error
+	for _, infoHash := range util.MapKeys(tagsByHash) {
+		tags := util.UniqueSlice(tagsByHash[infoHash])
+		slices.Sort(tags)
+		if !merge {
+			current, ok := currentTags[infoHash]
+			if !ok {
+				errs = append(errs, fmt.Errorf("torrent %s not found", infoHash))
+				continue
+			}
+			var removeTags []string
+			for _, tag := range current {
+				if !IsSubstituteTag(tag) && !slices.Contains(tags, tag) {
+					removeTags = append(removeTags, tag)
+				}
+			}
+			if len(removeTags) > 0 {
+				slices.Sort(removeTags)
+				key := strings.Join(removeTags, ",")
+				removeGroups[key] = append(removeGroups[key], infoHash)
+			}
+		}
+		if len(tags) > 0 {
+			key := strings.Join(tags, ",")
+			addGroups[key] = append(addGroups[key], infoHash)
+		}
+	}
+	for _, key := range util.MapKeys(removeGroups) {
+		if err := clientInstance.RemoveTagsFromTorrents(removeGroups[key], util.SplitCsv(key)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to remove tags %s from torrents %v: %w", key, removeGroups[key], err))
+		}
+	}
+	for _, key := range util.MapKeys(addGroups) {
+		if err := clientInstance.AddTagsToTorrents(addGroups[key], util.SplitCsv(key)); err != nil {
+			errs = append(errs, fmt.Errorf("failed to add tags %s to torrents %v: %w", key, addGroups[key], err))
+		}
+	}
+	return errors.Join(errs...)
+}
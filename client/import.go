@@ -0,0 +1,72 @@
+package client
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
+)
+
+// Import torrents into client from a CSV, which columns are: infohash, category, tags (comma-separated).
+// An optional header row (first column is "infohash") is skipped. torrentSource is used to get
+// the .torrent file contents of an info-hash. Torrents that already exist in client are skipped.
+// It does not stop on a row error, all row errors are joined and returned. Return the number of added torrents.
+func ImportTorrentsCSV(clientInstance Client, csvReader io.Reader,
+	torrentSource func(infoHash string) ([]byte, error)) (added int, err error) {
+	reader := csv.NewReader(csvReader)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	var errs []error
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return added, errors.Join(append(errs, fmt.Errorf("failed to read csv: %w", err))...)
+		}
+		if row == 1 && strings.EqualFold(record[0], "infohash") {
+			continue
+		}
+		if ok, err := importTorrentCsvRecord(clientInstance, record, torrentSource); err != nil {
+			errs = append(errs, fmt.Errorf("row %d: %w", row, err))
+		} else if ok {
+			added++
+		}
+	}
+	return added, errors.Join(errs...)
+}
+
+// Return true if the torrent of record is added to client.
+func importTorrentCsvRecord(clientInstance Client, record []string,
+	torrentSource func(infoHash string) ([]byte, error)) (bool, error) {
+	infoHash := strings.ToLower(strings.TrimSpace(record[0]))
+	if !IsValidInfoHash(infoHash) {
+		return false, fmt.Errorf("invalid info-hash %q", record[0])
+	}
+	option := &TorrentOption{Category: constants.NONE}
+	if len(record) > 1 && record[1] != "" {
+		option.Category = record[1]
+	}
+	if len(record) > 2 {
+		option.Tags = util.SplitCsv(record[2])
+	}
+	if torrent, err := clientInstance.GetTorrent(infoHash); err != nil {
+		return false, err
+	} else if torrent != nil {
+		return false, nil
+	}
+	torrentContent, err := torrentSource(infoHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to get torrent %s: %w", infoHash, err)
+	}
+	_, alreadyExisted, err := AddTorrentIdempotent(clientInstance, torrentContent, option, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to add torrent %s: %w", infoHash, err)
+	}
+	return !alreadyExisted, nil
+}
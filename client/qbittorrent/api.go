@@ -52,6 +52,18 @@ type apiTransferInfo struct {
 	Up_rate_limit      int64  `json:"up_rate_limit"`     //Upload rate limit (bytes/s)
 	Dht_nodes          int64  `json:"dht_nodes"`         //DHT nodes connected to
 	Connection_status  string `json:"connection_status"` //Connection status. connected|firewalled|disconnected
+	// Total peer connections. Only available in sync/maindata server_state
+	Total_peer_connections int64 `json:"total_peer_connections"`
+	// All-time download / upload (bytes). Only available in sync/maindata server_state
+	Alltime_dl int64 `json:"alltime_dl"`
+	Alltime_ul int64 `json:"alltime_ul"`
+}
+
+type apiLogEntry struct {
+	Id        int64  `json:"id"`        // ID of the message
+	Message   string `json:"message"`   // Text of the message
+	Timestamp int64  `json:"timestamp"` // Milliseconds since epoch
+	Type      int64  `json:"type"`      // Type of the message: Log::NORMAL: 1, Log::INFO: 2, Log::WARNING: 4, Log::CRITICAL: 8
 }
 
 type apiTorrentProperties struct {
@@ -96,9 +108,11 @@ type apiTorrentInfo struct {
 	Auto_tmm           bool    `json:"auto_tmm"`           //	bool	Whether this torrent is managed by Automatic Torrent Management
 	Availability       float64 `json:"availability"`       //	float	Percentage of file pieces currently available
 	Category           string  `json:"category"`           //	string	Category of the torrent
+	Comment            string  `json:"comment"`            //	string	Torrent comment (qb 5.0+)
 	Completed          int64   `json:"completed"`          //	integer	Amount of transfer data completed (bytes)
 	Completion_on      int64   `json:"completion_on"`      //	integer	Time (Unix Epoch) when the torrent completed
 	Content_path       string  `json:"content_path"`       //	string	Absolute path of torrent content (root path for multifile torrents; absolute file path for singlefile torrents)
+	Download_path      string  `json:"download_path"`      //	string	Path where incomplete torrent data is stored, "" if not used (qb 4.4+)
 	Dl_limit           int64   `json:"dl_limit"`           //	integer	Torrent download speed limit (bytes/s). -1 if ulimited.
 	Dlspeed            int64   `json:"dlspeed"`            //	integer	Torrent download speed (bytes/s)
 	Downloaded         int64   `json:"downloaded"`         //	integer	Amount of data downloaded
@@ -107,6 +121,8 @@ type apiTorrentInfo struct {
 	F_l_piece_prio     bool    `json:"f_l_piece_prio"`     //	bool	True if first last piece are prioritized
 	Force_start        bool    `json:"force_start"`        //	bool	True if force start is enabled for this torrent
 	Hash               string  `json:"hash"`               //	string	Torrent hash
+	Infohash_v1        string  `json:"infohash_v1"`        //	string	Torrent v1 info hash (qb 4.4+)
+	Infohash_v2        string  `json:"infohash_v2"`        //	string	Torrent v2 info hash (qb 4.4+)
 	Last_activity      int64   `json:"last_activity"`      //	integer	Last time (Unix Epoch) when a chunk was downloaded/uploaded
 	Magnet_uri         string  `json:"magnet_uri"`         //	string	Magnet URI corresponding to this torrent
 	Max_ratio          float64 `json:"max_ratio"`          //	float	Maximum share ratio until torrent is stopped from seeding/uploading
@@ -120,6 +136,7 @@ type apiTorrentInfo struct {
 	Progress           float64 `json:"progress"`           //	float	Torrent progress (percentage/100)
 	Ratio              float64 `json:"ratio"`              //	float	Torrent share ratio. Max ratio value: 9999.
 	Ratio_limit        float64 `json:"ratio_limit"`        //	float	TODO (what is different from max_ratio?)
+	Reannounce         int64   `json:"reannounce"`         //	integer	Time (seconds) until the next tracker reannounce
 	Save_path          string  `json:"save_path"`          //	string	Path where this torrent's data is stored
 	Seeding_time       int64   `json:"seeding_time"`       //	integer	Torrent elapsed time while complete (seconds)
 	Seeding_time_limit int64   `json:"seeding_time_limit"` //	integer	TODO (what is different from max_seeding_time?) seeding_time_limit is a per torrent setting when Automatic Torrent Management is disabled furthermore then max_seeding_time is set to seeding_time_limit for this torrent. If Automatic Torrent Management is enabled the value is -2. And if max_seeding_time is unset it have a default value -1.
@@ -141,6 +158,7 @@ type apiTorrentInfo struct {
 type apiPreferences struct {
 	Locale                                 string         `json:"locale"`                                 // Currently selected language (e.g. en_GB for English)
 	Create_subfolder_enabled               bool           `json:"create_subfolder_enabled"`               // True if a subfolder should be created when adding a torrent
+	Torrent_content_layout                 string         `json:"torrent_content_layout"`                 // Content layout of added torrents: Original|Subfolder|NoSubfolder (qb 4.3.2+)
 	Start_paused_enabled                   bool           `json:"start_paused_enabled"`                   // True if torrents should be added in a Paused state
 	Auto_delete_mode                       int64          `json:"auto_delete_mode"`                       // TODO
 	Preallocate_all                        bool           `json:"preallocate_all"`                        // True if disk space should be pre-allocated for all files
@@ -309,6 +327,8 @@ func (qbtorrent *apiTorrentInfo) ToTorrentState() string {
 		state = "paused"
 	case "checkingUP", "checkingDL", "checkingResumeData":
 		state = "checking"
+	case "moving":
+		state = "moving"
 	case "error", "missingFiles", "unknown":
 		state = "error"
 	default:
@@ -318,8 +338,27 @@ func (qbtorrent *apiTorrentInfo) ToTorrentState() string {
 }
 
 func (qbtorrent *apiTorrentInfo) ToTorrent() *client.Torrent {
+	// qb returns either -1 or 0 for "no limit", depending on version.
+	downloadSpeedLimit := qbtorrent.Dl_limit
+	if downloadSpeedLimit <= 0 {
+		downloadSpeedLimit = -1
+	}
+	uploadSpeedLimit := qbtorrent.Up_limit
+	if uploadSpeedLimit <= 0 {
+		uploadSpeedLimit = -1
+	}
+	nextAnnounceTime := int64(0)
+	if qbtorrent.Reannounce > 0 {
+		nextAnnounceTime = util.Now() + qbtorrent.Reannounce
+	}
+	ratio := qbtorrent.Ratio
+	if qbtorrent.Downloaded == 0 {
+		ratio = client.INFINITE_RATIO
+	}
 	torrent := &client.Torrent{
 		InfoHash:           qbtorrent.Hash,
+		InfoHashV1:         qbtorrent.Infohash_v1,
+		InfoHashV2:         qbtorrent.Infohash_v2,
 		Name:               qbtorrent.Name,
 		TrackerDomain:      util.ParseUrlHostname(qbtorrent.Tracker),
 		TrackerBaseDomain:  util.GetUrlDomain(qbtorrent.Tracker),
@@ -331,20 +370,28 @@ func (qbtorrent *apiTorrentInfo) ToTorrent() *client.Torrent {
 		ActivityTime:       qbtorrent.Last_activity,
 		Downloaded:         qbtorrent.Downloaded,
 		DownloadSpeed:      qbtorrent.Dlspeed,
-		DownloadSpeedLimit: qbtorrent.Dl_limit,
+		DownloadSpeedLimit: downloadSpeedLimit,
 		Uploaded:           qbtorrent.Uploaded,
 		UploadSpeed:        qbtorrent.Upspeed,
-		UploadedSpeedLimit: qbtorrent.Up_limit,
+		UploadSpeedLimit:   uploadSpeedLimit,
 		Category:           qbtorrent.Category,
 		SavePath:           qbtorrent.Save_path,
 		ContentPath:        qbtorrent.ContentPath(),
+		DownloadPath:       qbtorrent.DownloadPath(),
 		Tags:               util.SplitCsv(qbtorrent.Tags),
 		Seeders:            qbtorrent.Num_complete,
 		Size:               qbtorrent.Size,
 		SizeCompleted:      qbtorrent.Completed,
 		SizeTotal:          qbtorrent.Total_size,
 		Leechers:           qbtorrent.Num_incomplete,
+		Ratio:              ratio,
+		Priority:           max(qbtorrent.Priority, 0),
+		ConnectedPeers:     qbtorrent.Num_seeds + qbtorrent.Num_leechs,
+		Availability:       max(qbtorrent.Availability, -1),
+		NextAnnounceTime:   nextAnnounceTime,
 		Meta:               map[string]int64{},
+		Comment:            qbtorrent.Comment,
+		SourceUrl:          client.ParseSourceUrlFromComment(qbtorrent.Comment),
 	}
 	torrent.Name, torrent.Meta = client.ParseMetaFromName(torrent.Name)
 	return torrent
@@ -355,18 +402,38 @@ func (qbtorrent *apiTorrentInfo) ToTorrent() *client.Torrent {
 // E.g. if a torrent has a name "foo" and only one file "bar.txt" with a save path "/downloads",
 // Content_path will be "/downloads/foo/bar.txt".
 // This function returns "/downloads/foo" in this case.
+// If the torrent is incomplete and stored in a separate download path (incomplete dir), Content_path is in
+// download path, and this function returns the final content path in save path instead. See DownloadPath.
 func (qbtorrent *apiTorrentInfo) ContentPath() string {
+	if relativepath, ok := qbtorrent.relativeContentPath(qbtorrent.Download_path); ok {
+		return qbtorrent.Save_path + qbtorrent.Sep() + relativepath
+	}
+	if relativepath, ok := qbtorrent.relativeContentPath(qbtorrent.Save_path); ok {
+		return qbtorrent.Save_path + qbtorrent.Sep() + relativepath
+	}
+	return qbtorrent.Content_path
+}
+
+// Return the current content path of torrent in download path (incomplete dir),
+// or the same value as ContentPath if torrent is not stored there.
+func (qbtorrent *apiTorrentInfo) DownloadPath() string {
+	if relativepath, ok := qbtorrent.relativeContentPath(qbtorrent.Download_path); ok {
+		return qbtorrent.Download_path + qbtorrent.Sep() + relativepath
+	}
+	return qbtorrent.ContentPath()
+}
+
+// Return the root (first) component of Content_path relative to dir.
+func (qbtorrent *apiTorrentInfo) relativeContentPath(dir string) (string, bool) {
 	sep := qbtorrent.Sep()
-	cp := qbtorrent.Content_path
-	if !strings.HasPrefix(cp, qbtorrent.Save_path+sep) {
-		return cp
+	if dir == "" || !strings.HasPrefix(qbtorrent.Content_path, dir+sep) {
+		return "", false
 	}
-	relativepath := cp[len(qbtorrent.Save_path)+1:]
-	i := strings.Index(relativepath, sep)
-	if i == -1 {
-		return cp
+	relativepath := qbtorrent.Content_path[len(dir)+1:]
+	if i := strings.Index(relativepath, sep); i != -1 {
+		relativepath = relativepath[:i]
 	}
-	return qbtorrent.Save_path + sep + relativepath[:i]
+	return relativepath, true
 }
 
 // Return path sep (either '/' or '\') of this torrent.
@@ -18,6 +18,7 @@ import (
 	"slices"
 	"sort"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
@@ -82,6 +83,61 @@ func (qbclient *Client) SetTorrentsShareLimits(infoHashes []string, ratioLimit f
 	return qbclient.apiPost("api/v2/torrents/setShareLimits", data)
 }
 
+// qb API can only move torrent to top / bottom or one step up / down in queue,
+// so move it to top first, then move it down (priority - 1) steps.
+func (qbclient *Client) SetTorrentPriority(infoHash string, priority int64) error {
+	if priority <= 0 {
+		return fmt.Errorf("invalid priority %d", priority)
+	}
+	err := qbclient.login()
+	if err != nil {
+		return fmt.Errorf("login error: %w", err)
+	}
+	data := url.Values{
+		"hashes": {infoHash},
+	}
+	if err = qbclient.apiPost("api/v2/torrents/topPrio", data); err != nil {
+		return err
+	}
+	for i := int64(1); i < priority; i++ {
+		if err = qbclient.apiPost("api/v2/torrents/decreasePrio", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (qbclient *Client) SetTorrentsSpeedLimit(infoHashes []string,
+	downloadSpeedLimit int64, uploadSpeedLimit int64) error {
+	if len(infoHashes) == 0 {
+		return nil
+	}
+	err := qbclient.login()
+	if err != nil {
+		return fmt.Errorf("login error: %w", err)
+	}
+	// qb API: limit <= 0 means no limit
+	if downloadSpeedLimit != 0 {
+		data := url.Values{
+			"hashes": {strings.Join(infoHashes, "|")},
+			"limit":  {fmt.Sprint(max(downloadSpeedLimit, 0))},
+		}
+		if err := qbclient.apiPost("api/v2/torrents/setDownloadLimit", data); err != nil {
+			return err
+		}
+	}
+	if uploadSpeedLimit != 0 {
+		data := url.Values{
+			"hashes": {strings.Join(infoHashes, "|")},
+			"limit":  {fmt.Sprint(max(uploadSpeedLimit, 0))},
+		}
+		if err := qbclient.apiPost("api/v2/torrents/setUploadLimit", data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (qbclient *Client) apiPost(apiUrl string, data url.Values) error {
 	resp, err := qbclient.HttpClient.PostForm(qbclient.ClientConfig.Url+apiUrl, data)
 	if err != nil {
@@ -159,6 +215,21 @@ func (qbclient *Client) AddTorrent(torrentContent []byte, option *client.Torrent
 		return fmt.Errorf("login error: %w", err)
 	}
 	name := client.GenerateNameWithMeta(option.Name, meta)
+	contentLayout := "Original"
+	switch option.ContentLayout {
+	case "", "original":
+	case "subfolder":
+		contentLayout = "Subfolder"
+	case "nosubfolder":
+		contentLayout = "NoSubfolder"
+	default:
+		return fmt.Errorf("invalid content layout %q", option.ContentLayout)
+	}
+	if option.SourceUrl != "" && !util.IsTorrentUrl(string(torrentContent)) {
+		if torrentContent, err = client.SetTorrentContentSourceUrl(torrentContent, option.SourceUrl); err != nil {
+			return err
+		}
+	}
 	body := new(bytes.Buffer)
 	mp := multipart.NewWriter(body)
 	if util.IsTorrentUrl(string(torrentContent)) {
@@ -181,13 +252,14 @@ func (qbclient *Client) AddTorrent(torrentContent []byte, option *client.Torrent
 	// 3. 将 root_folder (true | false | <unset>) 替换为 contentLayout 字段: Original | Subfolder | NoSubfolder 。
 	// 为向下兼容，同时设置 4.X 和 5.X 的 API 字段。
 	mp.WriteField("rename", name)
-	mp.WriteField("root_folder", "true")
-	mp.WriteField("contentLayout", "Original")
+	mp.WriteField("root_folder", fmt.Sprint(contentLayout != "NoSubfolder")) // qb < 4.3.2
+	mp.WriteField("contentLayout", contentLayout)
 	if option != nil {
 		if option.Category != constants.NONE {
 			mp.WriteField("category", option.Category)
 		}
-		mp.WriteField("tags", strings.Join(option.Tags, ",")) // qb 4.3.2+ new
+		tags := client.GenerateAddTorrentTags(torrentContent, option.Tags)
+		mp.WriteField("tags", strings.Join(tags, ",")) // qb 4.3.2+ new
 		mp.WriteField("paused", fmt.Sprint(option.Pause))
 		mp.WriteField("stopped", fmt.Sprint(option.Pause))
 		if option.SkipChecking {
@@ -200,7 +272,7 @@ func (qbclient *Client) AddTorrent(torrentContent []byte, option *client.Torrent
 		mp.WriteField("upLimit", fmt.Sprint(option.UploadSpeedLimit))
 		mp.WriteField("dlLimit", fmt.Sprint(option.DownloadSpeedLimit))
 		if option.SavePath != "" {
-			mp.WriteField("savepath", option.SavePath)
+			mp.WriteField("savepath", torrentutil.ResolveOptionSavePath(torrentContent, option))
 			mp.WriteField("autoTMM", "false")
 		}
 		if option.SequentialDownload {
@@ -445,6 +517,68 @@ func (qbclient *Client) GetCategories() ([]*client.TorrentCategory, error) {
 	return cats, nil
 }
 
+// If category does not have a configured save path, qb use "<default save path>/<category>".
+func (qbclient *Client) GetCategorySavePath(category string) (string, error) {
+	categories, err := qbclient.GetCategories()
+	if err != nil {
+		return "", err
+	}
+	index := slices.IndexFunc(categories, func(cat *client.TorrentCategory) bool {
+		return cat.Name == category
+	})
+	if index == -1 {
+		return "", fmt.Errorf("category %q does not exist", category)
+	}
+	if categories[index].SavePath != "" {
+		return categories[index].SavePath, nil
+	}
+	preferences, err := qbclient.getPreferences()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(preferences.Save_path, "/") + "/" + category, nil
+}
+
+func (qbclient *Client) GetCategorySavePaths() (map[string]string, error) {
+	categories, err := qbclient.GetCategories()
+	if err != nil {
+		return nil, err
+	}
+	savePaths := map[string]string{}
+	for _, category := range categories {
+		if category.SavePath != "" {
+			savePaths[category.Name] = category.SavePath
+			continue
+		}
+		preferences, err := qbclient.getPreferences()
+		if err != nil {
+			return nil, err
+		}
+		savePaths[category.Name] = strings.TrimSuffix(preferences.Save_path, "/") + "/" + category.Name
+	}
+	return savePaths, nil
+}
+
+// qb Web API does not support editing comment.
+func (qbclient *Client) SetTorrentsComment(infoHashes []string, comment string) error {
+	return client.ErrUnsupported
+}
+
+func (qbclient *Client) SetTorrentsAutoManagement(infoHashes []string, enabled bool) error {
+	if len(infoHashes) == 0 {
+		return nil
+	}
+	err := qbclient.login()
+	if err != nil {
+		return fmt.Errorf("login error: %w", err)
+	}
+	data := url.Values{
+		"hashes": {strings.Join(infoHashes, "|")},
+		"enable": {fmt.Sprint(enabled)},
+	}
+	return qbclient.apiPost("api/v2/torrents/setAutoManagement", data)
+}
+
 func (qbclient *Client) SetTorrentsCatetory(infoHashes []string, category string) error {
 	if len(infoHashes) == 0 {
 		return nil
@@ -561,7 +695,7 @@ func (qbclient *Client) ModifyTorrent(infoHash string,
 		if len(removeTags) > 0 {
 			data := url.Values{
 				"hashes": {infoHash},
-				"tags":   {strings.Join(addTags, ",")},
+				"tags":   {strings.Join(removeTags, ",")},
 			}
 			err := qbclient.apiPost("api/v2/torrents/removeTags", data)
 			if err != nil {
@@ -580,26 +714,17 @@ func (qbclient *Client) ModifyTorrent(infoHash string,
 		}
 	}
 
-	if option.DownloadSpeedLimit != 0 && option.DownloadSpeedLimit != qbtorrent.Dl_limit {
-		data := url.Values{
-			"hashes": {infoHash},
-			"limit":  {fmt.Sprint(option.DownloadSpeedLimit)},
-		}
-		err := qbclient.apiPost("api/v2/torrents/setDownloadLimit", data)
-		if err != nil {
-			return err
-		}
+	torrent := qbtorrent.ToTorrent()
+	downloadSpeedLimit := int64(0)
+	uploadSpeedLimit := int64(0)
+	if option.DownloadSpeedLimit != 0 && max(option.DownloadSpeedLimit, -1) != torrent.DownloadSpeedLimit {
+		downloadSpeedLimit = option.DownloadSpeedLimit
 	}
-
-	if option.UploadSpeedLimit != 0 && option.UploadSpeedLimit != qbtorrent.Up_limit {
-		data := url.Values{
-			"hashes": {infoHash},
-			"limit":  {fmt.Sprint(option.UploadSpeedLimit)},
-		}
-		err := qbclient.apiPost("api/v2/torrents/setUploadLimit", data)
-		if err != nil {
-			return err
-		}
+	if option.UploadSpeedLimit != 0 && max(option.UploadSpeedLimit, -1) != torrent.UploadSpeedLimit {
+		uploadSpeedLimit = option.UploadSpeedLimit
+	}
+	if err := qbclient.SetTorrentsSpeedLimit([]string{infoHash}, downloadSpeedLimit, uploadSpeedLimit); err != nil {
+		return err
 	}
 
 	if option.RatioLimit != 0 || option.SeedingTimeLimit != 0 {
@@ -699,8 +824,15 @@ func (qbclient *Client) GetStatus() (*client.Status, error) {
 	status.DownloadSpeedLimit = qbclient.data.Server_state.Dl_rate_limit
 	status.UploadSpeedLimit = qbclient.data.Server_state.Up_rate_limit
 	status.FreeSpaceOnDisk = qbclient.data.Server_state.Free_space_on_disk
+	status.DiskTotal = -1 // qb does not report it
 	status.UnfinishedSize = qbclient.unfinishedSize
 	status.UnfinishedDownloadingSize = qbclient.unfinishedDownloadingSize
+	status.TotalConnections = qbclient.data.Server_state.Total_peer_connections
+	status.AllTimeDownloaded = qbclient.data.Server_state.Alltime_dl
+	status.AllTimeUploaded = qbclient.data.Server_state.Alltime_ul
+	for _, qbtorrent := range qbclient.data.Torrents {
+		status.TotalPeers += qbtorrent.Num_seeds + qbtorrent.Num_leechs
+	}
 	// @workaround
 	// qb 的 Web API 有 bug，有时 FreeSpaceOnDisk 返回 0，但实际硬盘剩余空间充足，原因尚不明确。
 	// 目前在 Windows QB 4.5.2 上发现此现象。
@@ -996,14 +1128,29 @@ func (qbclient *Client) GetTorrentTrackers(infoHash string) (client.TorrentTrack
 			status = "unknown"
 		}
 		return client.TorrentTracker{
-			Url:    qbtracker.Url,
-			Msg:    qbtracker.Msg,
-			Status: status,
+			Url:      qbtracker.Url,
+			Msg:      qbtracker.Msg,
+			Status:   status,
+			Seeders:  qbtracker.Num_seeds,
+			Leechers: qbtracker.Num_leeches,
+			Peers:    qbtracker.Num_peers,
 		}
 	})
 	return trackers, nil
 }
 
+func (qbclient *Client) GetTorrentsTrackers(infoHashes []string) (map[string]client.TorrentTrackers, error) {
+	torrentsTrackers := map[string]client.TorrentTrackers{}
+	for _, infoHash := range infoHashes {
+		trackers, err := qbclient.GetTorrentTrackers(infoHash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get torrent %s trackers: %w", infoHash, err)
+		}
+		torrentsTrackers[infoHash] = trackers
+	}
+	return torrentsTrackers, nil
+}
+
 func (qbclient *Client) EditTorrentTracker(infoHash string, oldTracker string,
 	newTracker string, replaceHost bool) error {
 	if replaceHost {
@@ -1120,6 +1267,215 @@ func (qbclient *Client) SetFilePriority(infoHash string, fileIndexes []int64, pr
 	return qbclient.apiPost("api/v2/torrents/filePrio", data)
 }
 
+// qb Web API does not provide a way to save resume data on demand.
+func (qbclient *Client) FlushState() error {
+	return client.ErrUnsupported
+}
+
+// qb encryption preference: 0 = prefer encryption, 1 = force encryption on, 2 = force encryption off.
+func (qbclient *Client) SetEncryptionMode(mode string) error {
+	index := slices.Index(client.ENCRYPTION_MODES, mode)
+	if index == -1 {
+		return fmt.Errorf("invalid encryption mode %q", mode)
+	}
+	if err := qbclient.login(); err != nil {
+		return fmt.Errorf("login error: %w", err)
+	}
+	return qbclient.setPreferences(map[string]any{"encryption": index})
+}
+
+func (qbclient *Client) GetEncryptionMode() (string, error) {
+	if err := qbclient.login(); err != nil {
+		return "", fmt.Errorf("login error: %w", err)
+	}
+	preferences, err := qbclient.getPreferences()
+	if err != nil {
+		return "", err
+	}
+	if preferences.Encryption < 0 || preferences.Encryption >= int64(len(client.ENCRYPTION_MODES)) {
+		return "", fmt.Errorf("unknown qb encryption value %d", preferences.Encryption)
+	}
+	return client.ENCRYPTION_MODES[preferences.Encryption], nil
+}
+
+func (qbclient *Client) GetBannedPeers() ([]string, error) {
+	if err := qbclient.login(); err != nil {
+		return nil, fmt.Errorf("login error: %w", err)
+	}
+	preferences, err := qbclient.getPreferences()
+	if err != nil {
+		return nil, err
+	}
+	// newline-delimited list
+	var peers []string
+	for _, ip := range strings.Split(preferences.Banned_IPs, "\n") {
+		if ip = strings.TrimSpace(ip); ip != "" {
+			peers = append(peers, ip)
+		}
+	}
+	return peers, nil
+}
+
+func (qbclient *Client) BanPeers(peers []string) error {
+	if len(peers) == 0 {
+		return nil
+	}
+	if err := qbclient.login(); err != nil {
+		return fmt.Errorf("login error: %w", err)
+	}
+	data := url.Values{
+		"peers": {strings.Join(peers, "|")},
+	}
+	return qbclient.apiPost("api/v2/transfer/banPeers", data)
+}
+
+func (qbclient *Client) GetLog(lastKnownId int64) ([]*client.LogEntry, int64, error) {
+	if err := qbclient.login(); err != nil {
+		return nil, lastKnownId, fmt.Errorf("login error: %w", err)
+	}
+	var qblogs []*apiLogEntry
+	if err := qbclient.apiRequest(fmt.Sprintf("api/v2/log/main?last_known_id=%d", lastKnownId), &qblogs); err != nil {
+		return nil, lastKnownId, err
+	}
+	levels := map[int64]string{1: "normal", 2: "info", 4: "warning", 8: "critical"}
+	var entries []*client.LogEntry
+	maxId := lastKnownId
+	for _, qblog := range qblogs {
+		entries = append(entries, &client.LogEntry{
+			Id:      qblog.Id,
+			Time:    qblog.Timestamp / 1000,
+			Level:   levels[qblog.Type],
+			Message: qblog.Message,
+		})
+		maxId = max(maxId, qblog.Id)
+	}
+	return entries, maxId, nil
+}
+
+func (qbclient *Client) GetGlobalPeerDiscovery() (dht bool, pex bool, lsd bool, err error) {
+	if err = qbclient.login(); err != nil {
+		return false, false, false, fmt.Errorf("login error: %w", err)
+	}
+	preferences, err := qbclient.getPreferences()
+	if err != nil {
+		return false, false, false, err
+	}
+	return preferences.Dht, preferences.Pex, preferences.Lsd, nil
+}
+
+func (qbclient *Client) SetGlobalPeerDiscovery(dht bool, pex bool, lsd bool) error {
+	if err := qbclient.login(); err != nil {
+		return fmt.Errorf("login error: %w", err)
+	}
+	return qbclient.setPreferences(map[string]any{"dht": dht, "pex": pex, "lsd": lsd})
+}
+
+// qb scheduler_days: 0 = every day, 1 = weekdays, 2 = weekends, 3-9 = Monday - Sunday.
+var qbSchedulerDays = [][]time.Weekday{
+	{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday},
+	{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	{time.Sunday, time.Saturday},
+	{time.Monday},
+	{time.Tuesday},
+	{time.Wednesday},
+	{time.Thursday},
+	{time.Friday},
+	{time.Saturday},
+	{time.Sunday},
+}
+
+func (qbclient *Client) GetScheduler() (*client.SchedulerConfig, error) {
+	if err := qbclient.login(); err != nil {
+		return nil, fmt.Errorf("login error: %w", err)
+	}
+	preferences, err := qbclient.getPreferences()
+	if err != nil {
+		return nil, err
+	}
+	if preferences.Scheduler_days < 0 || preferences.Scheduler_days >= int64(len(qbSchedulerDays)) {
+		return nil, fmt.Errorf("unknown qb scheduler_days value %d", preferences.Scheduler_days)
+	}
+	return &client.SchedulerConfig{
+		Enabled: preferences.Scheduler_enabled,
+		From:    preferences.Schedule_from_hour*60 + preferences.Schedule_from_min,
+		To:      preferences.Schedule_to_hour*60 + preferences.Schedule_to_min,
+		Days:    slices.Clone(qbSchedulerDays[preferences.Scheduler_days]),
+	}, nil
+}
+
+// qb only supports every day, weekdays, weekends or a single day.
+func (qbclient *Client) SetScheduler(scheduler *client.SchedulerConfig) error {
+	days := 0
+	if len(scheduler.Days) > 0 {
+		sortedDays := slices.Compact(slices.Sorted(slices.Values(scheduler.Days)))
+		days = slices.IndexFunc(qbSchedulerDays, func(qbdays []time.Weekday) bool {
+			return slices.Equal(qbdays, sortedDays)
+		})
+		if days == -1 {
+			return fmt.Errorf("qb does not support scheduler days %v", scheduler.Days)
+		}
+	}
+	if err := qbclient.login(); err != nil {
+		return fmt.Errorf("login error: %w", err)
+	}
+	return qbclient.setPreferences(map[string]any{
+		"scheduler_enabled":  scheduler.Enabled,
+		"schedule_from_hour": scheduler.From / 60,
+		"schedule_from_min":  scheduler.From % 60,
+		"schedule_to_hour":   scheduler.To / 60,
+		"schedule_to_min":    scheduler.To % 60,
+		"scheduler_days":     days,
+	})
+}
+
+func (qbclient *Client) GetGlobalMaxUploads() (int, error) {
+	if err := qbclient.login(); err != nil {
+		return 0, fmt.Errorf("login error: %w", err)
+	}
+	preferences, err := qbclient.getPreferences()
+	if err != nil {
+		return 0, err
+	}
+	if preferences.Max_uploads <= 0 {
+		return -1, nil
+	}
+	return int(preferences.Max_uploads), nil
+}
+
+func (qbclient *Client) GetAddDefaults() (*client.AddDefaults, error) {
+	if err := qbclient.login(); err != nil {
+		return nil, fmt.Errorf("login error: %w", err)
+	}
+	preferences, err := qbclient.getPreferences()
+	if err != nil {
+		return nil, err
+	}
+	contentLayout := strings.ToLower(preferences.Torrent_content_layout)
+	if contentLayout == "" {
+		// qb < 4.3.2, which has no "subfolder" option.
+		if preferences.Create_subfolder_enabled {
+			contentLayout = "original"
+		} else {
+			contentLayout = "nosubfolder"
+		}
+	}
+	return &client.AddDefaults{
+		SavePath:      preferences.Save_path,
+		Paused:        preferences.Start_paused_enabled,
+		ContentLayout: contentLayout,
+	}, nil
+}
+
+func (qbclient *Client) SetGlobalMaxUploads(n int) error {
+	if n <= 0 {
+		n = -1
+	}
+	if err := qbclient.login(); err != nil {
+		return fmt.Errorf("login error: %w", err)
+	}
+	return qbclient.setPreferences(map[string]any{"max_uploads": n})
+}
+
 func (qbclient *Client) Close() {
 	qbclient.PurgeCache()
 	if qbclient.Logined && !qbclient.ClientConfig.QbittorrentNoLogout {
@@ -1128,6 +1484,14 @@ func (qbclient *Client) Close() {
 	}
 }
 
+func validateConfig(clientConfig *config.ClientConfigStruct) error {
+	urlObj, err := url.Parse(clientConfig.Url)
+	if err != nil || (urlObj.Scheme != "http" && urlObj.Scheme != "https") || urlObj.Hostname() == "" {
+		return fmt.Errorf("invalid qb url: %q", clientConfig.Url)
+	}
+	return nil
+}
+
 func NewClient(name string, clientConfig *config.ClientConfigStruct, config *config.ConfigStruct) (
 	client.Client, error) {
 	jar, err := cookiejar.New(nil)
@@ -1147,8 +1511,9 @@ func NewClient(name string, clientConfig *config.ClientConfigStruct, config *con
 
 func init() {
 	client.Register(&client.RegInfo{
-		Name:    "qbittorrent",
-		Creator: NewClient,
+		Name:      "qbittorrent",
+		Creator:   NewClient,
+		Validator: validateConfig,
 	})
 }
 
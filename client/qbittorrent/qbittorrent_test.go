@@ -0,0 +1,86 @@
+package qbittorrent_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/sagan/ptool/client/qbittorrent"
+	"github.com/sagan/ptool/config"
+)
+
+const testInfoHash = "0123456789abcdef0123456789abcdef01234567"
+
+// A minimal fake qb Web API server that holds speed limits of a single torrent.
+func newFakeQbServer(t *testing.T) *httptest.Server {
+	limits := map[string]int64{"dl_limit": 0, "up_limit": 0}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v2/sync/maindata", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"server_state": map[string]any{},
+			"torrents": map[string]any{
+				testInfoHash: map[string]any{
+					"name":     "test",
+					"state":    "uploading",
+					"dl_limit": limits["dl_limit"],
+					"up_limit": limits["up_limit"],
+				},
+			},
+		})
+	})
+	setLimit := func(field string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.PostFormValue("hashes") != testInfoHash {
+				t.Errorf("%s: unexpected hashes %q", r.URL.Path, r.PostFormValue("hashes"))
+			}
+			limit, err := strconv.ParseInt(r.PostFormValue("limit"), 10, 64)
+			if err != nil {
+				t.Errorf("%s: invalid limit %q", r.URL.Path, r.PostFormValue("limit"))
+			}
+			limits[field] = limit
+		}
+	}
+	mux.HandleFunc("/api/v2/torrents/setDownloadLimit", setLimit("dl_limit"))
+	mux.HandleFunc("/api/v2/torrents/setUploadLimit", setLimit("up_limit"))
+	return httptest.NewServer(mux)
+}
+
+func TestSetTorrentsSpeedLimit(t *testing.T) {
+	server := newFakeQbServer(t)
+	defer server.Close()
+	clientInstance, err := qbittorrent.NewClient("test", &config.ClientConfigStruct{
+		Url:                server.URL + "/",
+		QbittorrentNoLogin: true,
+	}, nil)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	tests := []struct {
+		downloadSpeedLimit int64
+		uploadSpeedLimit   int64
+		wantDownloadLimit  int64
+		wantUploadLimit    int64
+	}{
+		{downloadSpeedLimit: 1024, uploadSpeedLimit: 2048, wantDownloadLimit: 1024, wantUploadLimit: 2048},
+		{downloadSpeedLimit: 0, uploadSpeedLimit: -1, wantDownloadLimit: 1024, wantUploadLimit: -1},
+		{downloadSpeedLimit: -1, uploadSpeedLimit: 0, wantDownloadLimit: -1, wantUploadLimit: -1},
+	}
+	for _, tt := range tests {
+		err := clientInstance.SetTorrentsSpeedLimit([]string{testInfoHash}, tt.downloadSpeedLimit, tt.uploadSpeedLimit)
+		if err != nil {
+			t.Fatalf("SetTorrentsSpeedLimit(%d, %d) error: %v", tt.downloadSpeedLimit, tt.uploadSpeedLimit, err)
+		}
+		clientInstance.PurgeCache()
+		torrent, err := clientInstance.GetTorrent(testInfoHash)
+		if err != nil || torrent == nil {
+			t.Fatalf("GetTorrent error: %v", err)
+		}
+		if torrent.DownloadSpeedLimit != tt.wantDownloadLimit || torrent.UploadSpeedLimit != tt.wantUploadLimit {
+			t.Errorf("SetTorrentsSpeedLimit(%d, %d): got limits %d / %d, want %d / %d",
+				tt.downloadSpeedLimit, tt.uploadSpeedLimit, torrent.DownloadSpeedLimit, torrent.UploadSpeedLimit,
+				tt.wantDownloadLimit, tt.wantUploadLimit)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sagan/ptool/util"
+)
+
+// Max number of torrents listed in "Top uploading" section of ReportMarkdown.
+const REPORT_TOP_TORRENTS = 10
+
+// InfluxDB line protocol measurements written by PrintTorrentsInflux.
+const (
+	INFLUX_MEASUREMENT_TORRENT = "ptool_torrent"
+	INFLUX_MEASUREMENT_CLIENT  = "ptool_client"
+)
+
+// Escape label values of Prometheus text format.
+var prometheusLabelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+var markdownTableCellReplacer = strings.NewReplacer("|", `\|`, "\n", " ", "\r", "")
+
+// Escape tag values of InfluxDB line protocol. Line breaks are not allowed and are converted to spaces.
+var influxTagReplacer = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `, "\n", `\ `, "\r", "")
+
+// Write a Markdown report of client to w: status, summary of (filtered) torrents, per-state counts
+// and top uploading torrents. It's suitable for pasting into chats or issues.
+// status is optional. f is optional, if not nil, only torrents that match it are included.
+func ReportMarkdown(w io.Writer, name string, status *Status, torrents []*Torrent, f *TorrentFilter) error {
+	torrents = FilterTorrents(torrents, f)
+	summary := SummarizeTorrents(torrents)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Client %s\n\n", markdownTableCellReplacer.Replace(name))
+	if status != nil {
+		freeSpace := "unknown"
+		if status.FreeSpaceOnDisk >= 0 {
+			freeSpace = util.BytesSize(float64(status.FreeSpaceOnDisk))
+		}
+		fmt.Fprintf(&sb, "## Status\n\n| Free space | ↓Speed | ↑Speed | Peers |\n| --- | --- | --- | --- |\n")
+		fmt.Fprintf(&sb, "| %s | %s/s | %s/s | %d |\n\n", freeSpace,
+			util.BytesSize(float64(status.DownloadSpeed)), util.BytesSize(float64(status.UploadSpeed)), status.TotalPeers)
+	}
+	fmt.Fprintf(&sb, "## Summary\n\n| Torrents | Size | Completed | Downloaded | Uploaded | ↓Speed | ↑Speed |\n")
+	fmt.Fprintf(&sb, "| --- | --- | --- | --- | --- | --- | --- |\n")
+	fmt.Fprintf(&sb, "| %d | %s | %s | %s | %s | %s/s | %s/s |\n\n", summary.Count,
+		util.BytesSize(float64(summary.Size)), util.BytesSize(float64(summary.SizeCompleted)),
+		util.BytesSize(float64(summary.Downloaded)), util.BytesSize(float64(summary.Uploaded)),
+		util.BytesSize(float64(summary.DownloadSpeed)), util.BytesSize(float64(summary.UploadSpeed)))
+	fmt.Fprintf(&sb, "## States\n\n| State | Count |\n| --- | --- |\n")
+	for _, state := range STATES {
+		if summary.StateCounts[state] > 0 {
+			fmt.Fprintf(&sb, "| %s | %d |\n", state, summary.StateCounts[state])
+		}
+	}
+	sb.WriteString("\n")
+	if topTorrents := TopUploadingTorrents(torrents, REPORT_TOP_TORRENTS); len(topTorrents) > 0 {
+		fmt.Fprintf(&sb, "## Top uploading\n\n| Name | Size | ↑Speed | Tracker |\n| --- | --- | --- | --- |\n")
+		for _, torrent := range topTorrents {
+			fmt.Fprintf(&sb, "| %s | %s | %s/s | %s |\n", markdownTableCellReplacer.Replace(torrent.Name),
+				util.BytesSize(float64(torrent.Size)), util.BytesSize(float64(torrent.UploadSpeed)), torrent.TrackerDomain)
+		}
+		sb.WriteString("\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Write torrents and client statistics as InfluxDB line protocol records to w, e.g. to be fed into telegraf.
+// Each torrent is a INFLUX_MEASUREMENT_TORRENT record, tagged with client, infohash, site, category and state.
+// An aggregate INFLUX_MEASUREMENT_CLIENT record of all torrents (and status, which is optional)
+// is tagged only with client, to keep the tag cardinality low. Empty tags are omitted.
+func PrintTorrentsInflux(w io.Writer, clientName string, torrents []*Torrent, status *Status,
+	timestamp time.Time) error {
+	var sb strings.Builder
+	ts := timestamp.UnixNano()
+	for _, torrent := range torrents {
+		sb.WriteString(INFLUX_MEASUREMENT_TORRENT)
+		writeInfluxTags(&sb, "client", clientName, "infohash", torrent.InfoHash, "site", torrent.GetSite(),
+			"category", torrent.Category, "state", torrent.State)
+		fmt.Fprintf(&sb, " size=%di,size_completed=%di,downloaded=%di,uploaded=%di,"+
+			"download_speed=%di,upload_speed=%di,ratio=%f %d\n",
+			torrent.Size, torrent.SizeCompleted, torrent.Downloaded, torrent.Uploaded,
+			torrent.DownloadSpeed, torrent.UploadSpeed, torrent.Ratio, ts)
+	}
+	summary := SummarizeTorrents(torrents)
+	sb.WriteString(INFLUX_MEASUREMENT_CLIENT)
+	writeInfluxTags(&sb, "client", clientName)
+	fmt.Fprintf(&sb, " torrents=%di,size=%di,size_completed=%di,downloaded=%di,uploaded=%di",
+		summary.Count, summary.Size, summary.SizeCompleted, summary.Downloaded, summary.Uploaded)
+	if status != nil {
+		fmt.Fprintf(&sb, ",download_speed=%di,upload_speed=%di,total_peers=%di",
+			status.DownloadSpeed, status.UploadSpeed, status.TotalPeers)
+		if status.FreeSpaceOnDisk >= 0 {
+			fmt.Fprintf(&sb, ",free_space=%di", status.FreeSpaceOnDisk)
+		}
+	} else {
+		fmt.Fprintf(&sb, ",download_speed=%di,upload_speed=%di", summary.DownloadSpeed, summary.UploadSpeed)
+	}
+	fmt.Fprintf(&sb, " %d\n", ts)
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// Write ",key=value" tags to sb. keyValues: key1, value1, key2, value2... Empty values are skipped.
+func writeInfluxTags(sb *strings.Builder, keyValues ...string) {
+	for i := 0; i+1 < len(keyValues); i += 2 {
+		if keyValues[i+1] == "" {
+			continue
+		}
+		fmt.Fprintf(sb, ",%s=%s", keyValues[i], influxTagReplacer.Replace(keyValues[i+1]))
+	}
+}
+
+// Write a snapshot of client status and torrents as Prometheus text format metrics to w,
+// e.g. into a file read by node_exporter textfile collector. All metrics are gauges labeled with client.
+// Torrents are aggregated by state and by site & state, so the label cardinality is bounded (no per-torrent labels).
+// status is optional.
+func WritePrometheus(w io.Writer, clientName string, status *Status, torrents []*Torrent) error {
+	var sb strings.Builder
+	clientLabel := prometheusLabelReplacer.Replace(clientName)
+	writeGauge := func(name, help string) {
+		fmt.Fprintf(&sb, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+	if status != nil {
+		if status.FreeSpaceOnDisk >= 0 {
+			writeGauge("ptool_client_free_space_bytes", "Free disk space of default save path.")
+			fmt.Fprintf(&sb, "ptool_client_free_space_bytes{client=\"%s\"} %d\n", clientLabel, status.FreeSpaceOnDisk)
+		}
+		writeGauge("ptool_client_download_speed_bytes", "Current download speed (bytes/s).")
+		fmt.Fprintf(&sb, "ptool_client_download_speed_bytes{client=\"%s\"} %d\n", clientLabel, status.DownloadSpeed)
+		writeGauge("ptool_client_upload_speed_bytes", "Current upload speed (bytes/s).")
+		fmt.Fprintf(&sb, "ptool_client_upload_speed_bytes{client=\"%s\"} %d\n", clientLabel, status.UploadSpeed)
+	}
+	summary := SummarizeTorrents(torrents)
+	writeGauge("ptool_client_torrents", "Number of torrents by state.")
+	for _, state := range util.MapKeys(summary.StateCounts) {
+		fmt.Fprintf(&sb, "ptool_client_torrents{client=\"%s\",state=\"%s\"} %d\n",
+			clientLabel, prometheusLabelReplacer.Replace(state), summary.StateCounts[state])
+	}
+	uploaded := map[string]map[string]int64{} // site => state => uploaded
+	for _, torrent := range torrents {
+		site := torrent.GetSite()
+		if uploaded[site] == nil {
+			uploaded[site] = map[string]int64{}
+		}
+		uploaded[site][torrent.State] += torrent.Uploaded
+	}
+	writeGauge("ptool_site_uploaded_bytes", "Total uploaded of torrents by site and state.")
+	for _, site := range util.MapKeys(uploaded) {
+		for _, state := range util.MapKeys(uploaded[site]) {
+			fmt.Fprintf(&sb, "ptool_site_uploaded_bytes{client=\"%s\",site=\"%s\",state=\"%s\"} %d\n", clientLabel,
+				prometheusLabelReplacer.Replace(site), prometheusLabelReplacer.Replace(state), uploaded[site][state])
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
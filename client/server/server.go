@@ -0,0 +1,187 @@
+// Package server exposes a Client over HTTP as a uniform JSON API, regardless of underlying client type.
+//
+// Endpoints (all responses are JSON):
+//
+//	GET  /api/status                                  GetStatus
+//	GET  /api/torrents?filter=&category=&showAll=     GetTorrents. filter is the stateFilter, default "_all"
+//	GET  /api/torrents/{infoHash}                     GetTorrent
+//	POST /api/torrents/pause                          PauseTorrents
+//	POST /api/torrents/resume                         ResumeTorrents
+//	POST /api/torrents/recheck                        RecheckTorrents
+//	POST /api/torrents/reannounce                     ReannounceTorrents
+//	POST /api/torrents/delete                         DeleteTorrents
+//	POST /api/torrents/addTags                        AddTagsToTorrents
+//	POST /api/torrents/removeTags                     RemoveTagsFromTorrents
+//
+// Write endpoints accept a form with "hashes" (comma-separated info-hashes); "deleteFiles" (bool) is used by delete
+// and "tags" (comma-separated) by addTags / removeTags.
+// If token is set, requests must have a "Authorization: Bearer <token>" header.
+// If token is not set, server is always read-only, as write endpoints (e.g. delete) must not be unauthenticated.
+// Serve reads token and read-only flag from client's "serverToken" and "serverReadOnly" config.
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/util"
+)
+
+const (
+	READ_HEADER_TIMEOUT = 10 * time.Second
+	READ_TIMEOUT        = 30 * time.Second
+	WRITE_TIMEOUT       = 60 * time.Second // GetTorrents of a large client may be slow
+	IDLE_TIMEOUT        = 120 * time.Second
+)
+
+type Server struct {
+	client   client.Client
+	token    string
+	readOnly bool
+	mux      *http.ServeMux
+}
+
+// Create a http handler that exposes clientInstance. If token is not empty, it's required in requests.
+// If readOnly is true, all write endpoints return 403. Empty token forces readOnly.
+func NewServer(clientInstance client.Client, token string, readOnly bool) *Server {
+	if token == "" && !readOnly {
+		log.Warnf("No token is set for the HTTP API of client %s, it's forced to be read-only",
+			clientInstance.GetName())
+		readOnly = true
+	}
+	server := &Server{
+		client:   clientInstance,
+		token:    token,
+		readOnly: readOnly,
+		mux:      http.NewServeMux(),
+	}
+	server.mux.HandleFunc("GET /api/status", server.handleStatus)
+	server.mux.HandleFunc("GET /api/torrents", server.handleTorrents)
+	server.mux.HandleFunc("GET /api/torrents/{infoHash}", server.handleTorrent)
+	writeActions := map[string]func(infoHashes []string, r *http.Request) error{
+		"pause":   func(infoHashes []string, r *http.Request) error { return clientInstance.PauseTorrents(infoHashes) },
+		"resume":  func(infoHashes []string, r *http.Request) error { return clientInstance.ResumeTorrents(infoHashes) },
+		"recheck": func(infoHashes []string, r *http.Request) error { return clientInstance.RecheckTorrents(infoHashes) },
+		"reannounce": func(infoHashes []string, r *http.Request) error {
+			return clientInstance.ReannounceTorrents(infoHashes)
+		},
+		"delete": func(infoHashes []string, r *http.Request) error {
+			deleteFiles, _ := strconv.ParseBool(r.PostFormValue("deleteFiles"))
+			return clientInstance.DeleteTorrents(infoHashes, deleteFiles)
+		},
+		"addTags": func(infoHashes []string, r *http.Request) error {
+			return clientInstance.AddTagsToTorrents(infoHashes, util.SplitCsv(r.PostFormValue("tags")))
+		},
+		"removeTags": func(infoHashes []string, r *http.Request) error {
+			return clientInstance.RemoveTagsFromTorrents(infoHashes, util.SplitCsv(r.PostFormValue("tags")))
+		},
+	}
+	for action, fn := range writeActions {
+		server.mux.HandleFunc("POST /api/torrents/"+action, server.writeHandler(fn))
+	}
+	return server
+}
+
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if server.token != "" {
+		authorization := r.Header.Get("Authorization")
+		if subtle.ConstantTimeCompare([]byte(authorization), []byte("Bearer "+server.token)) != 1 {
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid token"))
+			return
+		}
+	}
+	server.mux.ServeHTTP(w, r)
+}
+
+func (server *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status, err := server.client.GetStatus()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJson(w, status)
+}
+
+func (server *Server) handleTorrents(w http.ResponseWriter, r *http.Request) {
+	stateFilter := r.URL.Query().Get("filter")
+	if stateFilter == "" {
+		stateFilter = "_all"
+	}
+	showAll, _ := strconv.ParseBool(r.URL.Query().Get("showAll"))
+	torrents, err := server.client.GetTorrents(stateFilter, r.URL.Query().Get("category"), showAll)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	writeJson(w, torrents)
+}
+
+func (server *Server) handleTorrent(w http.ResponseWriter, r *http.Request) {
+	torrent, err := server.client.GetTorrent(r.PathValue("infoHash"))
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	if torrent == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("torrent not found"))
+		return
+	}
+	writeJson(w, torrent)
+}
+
+func (server *Server) writeHandler(fn func(infoHashes []string, r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if server.readOnly {
+			writeError(w, http.StatusForbidden, fmt.Errorf("server is read-only"))
+			return
+		}
+		infoHashes := util.SplitCsv(r.PostFormValue("hashes"))
+		if len(infoHashes) == 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("hashes is required"))
+			return
+		}
+		if err := fn(infoHashes, r); err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		server.client.PurgeCache()
+		writeJson(w, map[string]any{"ok": true})
+	}
+}
+
+func writeJson(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Debugf("Failed to write response: %v", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]any{"error": err.Error()})
+}
+
+// Expose clientInstance over HTTP at addr (e.g. "127.0.0.1:8000"). Block until server fails.
+// Token and read-only flag are read from client's config, see NewServer.
+func Serve(addr string, clientInstance client.Client) error {
+	clientConfig := clientInstance.GetClientConfig()
+	server := NewServer(clientInstance, clientConfig.ServerToken, clientConfig.ServerReadOnly)
+	log.Infof("Serving client %s at %s (read-only: %t)", clientInstance.GetName(), addr, server.readOnly)
+	httpServer := &http.Server{
+		Addr:              addr,
+		Handler:           server,
+		ReadHeaderTimeout: READ_HEADER_TIMEOUT,
+		ReadTimeout:       READ_TIMEOUT,
+		WriteTimeout:      WRITE_TIMEOUT,
+		IdleTimeout:       IDLE_TIMEOUT,
+	}
+	return httpServer.ListenAndServe()
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sagan/ptool/client"
+)
+
+// A fake client which only implements methods used by tests. Other methods panic.
+type fakeClient struct {
+	client.Client
+	torrents []*client.Torrent
+	paused   []string
+}
+
+func (c *fakeClient) GetStatus() (*client.Status, error) {
+	return &client.Status{DownloadSpeed: 100}, nil
+}
+
+func (c *fakeClient) GetTorrents(stateFilter string, category string, showAll bool) ([]*client.Torrent, error) {
+	return c.torrents, nil
+}
+
+func (c *fakeClient) GetTorrent(infoHash string) (*client.Torrent, error) {
+	for _, torrent := range c.torrents {
+		if torrent.InfoHash == infoHash {
+			return torrent, nil
+		}
+	}
+	return nil, nil
+}
+
+func (c *fakeClient) PauseTorrents(infoHashes []string) error {
+	c.paused = append(c.paused, infoHashes...)
+	return nil
+}
+
+func (c *fakeClient) PurgeCache() {
+}
+
+func (c *fakeClient) GetName() string {
+	return "fake"
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{torrents: []*client.Torrent{{InfoHash: "a", Name: "foo"}, {InfoHash: "b", Name: "bar"}}}
+}
+
+func request(handler http.Handler, method string, path string, token string,
+	form url.Values) *httptest.ResponseRecorder {
+	var req *http.Request
+	if form != nil {
+		req = httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req = httptest.NewRequest(method, path, nil)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestServerToken(t *testing.T) {
+	server := NewServer(newFakeClient(), "secret", false)
+	tests := []struct {
+		token string
+		want  int
+	}{
+		{"", http.StatusUnauthorized},
+		{"wrong", http.StatusUnauthorized},
+		{"secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if got := request(server, "GET", "/api/status", tt.token, nil).Code; got != tt.want {
+			t.Errorf("GET /api/status with token %q = %d, want %d", tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestServerReadOnly(t *testing.T) {
+	clientInstance := newFakeClient()
+	form := url.Values{"hashes": {"a,b"}}
+	w := request(NewServer(clientInstance, "", true), "POST", "/api/torrents/pause", "", form)
+	if w.Code != http.StatusForbidden || len(clientInstance.paused) > 0 {
+		t.Errorf("read-only pause = %d (paused %v), want %d", w.Code, clientInstance.paused, http.StatusForbidden)
+	}
+	w = request(NewServer(clientInstance, "", false), "POST", "/api/torrents/pause", "", form)
+	if w.Code != http.StatusForbidden || len(clientInstance.paused) > 0 {
+		t.Errorf("pause without token = %d (paused %v), want %d", w.Code, clientInstance.paused, http.StatusForbidden)
+	}
+	w = request(NewServer(clientInstance, "secret", false), "POST", "/api/torrents/pause", "secret", form)
+	if w.Code != http.StatusOK || !reflect.DeepEqual(clientInstance.paused, []string{"a", "b"}) {
+		t.Errorf("pause = %d (paused %v), want %d", w.Code, clientInstance.paused, http.StatusOK)
+	}
+}
+
+func TestServerTorrents(t *testing.T) {
+	server := NewServer(newFakeClient(), "", false)
+
+	w := request(server, "GET", "/api/torrents", "", nil)
+	var torrents []*client.Torrent
+	if err := json.Unmarshal(w.Body.Bytes(), &torrents); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("GET /api/torrents = %d %s, err %v", w.Code, w.Body.String(), err)
+	}
+	if len(torrents) != 2 || torrents[0].InfoHash != "a" || torrents[1].Name != "bar" {
+		t.Errorf("GET /api/torrents = %v", torrents)
+	}
+
+	w = request(server, "GET", "/api/torrents/b", "", nil)
+	var torrent *client.Torrent
+	if err := json.Unmarshal(w.Body.Bytes(), &torrent); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("GET /api/torrents/b = %d %s, err %v", w.Code, w.Body.String(), err)
+	}
+	if torrent.Name != "bar" {
+		t.Errorf("GET /api/torrents/b = %v", torrent)
+	}
+
+	if w = request(server, "GET", "/api/torrents/c", "", nil); w.Code != http.StatusNotFound {
+		t.Errorf("GET /api/torrents/c = %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,372 @@
+package client
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sagan/ptool/util"
+)
+
+// Returned by TimeToDiskFull if the time can not be estimated.
+const DISK_FULL_UNKNOWN = time.Duration(-1)
+
+// Sum torrents size per category. Uncategorized torrents are summed under "" key.
+// If completedOnly is true, sum SizeCompleted (actual on-disk usage) instead of Size.
+func CategoryDiskUsage(torrents []*Torrent, completedOnly bool) map[string]int64 {
+	usage := map[string]int64{}
+	for _, torrent := range torrents {
+		if completedOnly {
+			usage[torrent.Category] += torrent.SizeCompleted
+		} else {
+			usage[torrent.Category] += torrent.Size
+		}
+	}
+	return usage
+}
+
+// Group torrents info-hashes by TrackerDomain. Info-hashes are lower-cased and sorted for stable diffs.
+// Torrents without a tracker are grouped under "" key. Callers should filter torrents in ahead if needed
+// (e.g. only seeding ones).
+func TorrentsByTracker(torrents []*Torrent) map[string][]string {
+	trackerInfoHashes := map[string][]string{}
+	for _, torrent := range torrents {
+		trackerInfoHashes[torrent.TrackerDomain] = append(trackerInfoHashes[torrent.TrackerDomain],
+			strings.ToLower(torrent.InfoHash))
+	}
+	for _, infoHashes := range trackerInfoHashes {
+		slices.Sort(infoHashes)
+	}
+	return trackerInfoHashes
+}
+
+// A torrent with derived groupings, computed once for rendering.
+type AnnotatedTorrent struct {
+	*Torrent
+	Site          string           // site name, see Torrent.GetSite
+	TrackerDomain string           // lower-cased tracker domain (hostname)
+	DisplayName   string           // name with "__meta." suffix (if any) stripped
+	Meta          map[string]int64 // meta of torrent, including those parsed from name. Never nil
+}
+
+// Annotate each torrent with it's site, normalized tracker domain and parsed display name & meta.
+func AnnotateTorrents(torrents []*Torrent) []*AnnotatedTorrent {
+	annotatedTorrents := make([]*AnnotatedTorrent, 0, len(torrents))
+	for _, torrent := range torrents {
+		name, nameMeta := ParseMetaFromName(torrent.Name)
+		meta := map[string]int64{}
+		for key, value := range nameMeta {
+			meta[key] = value
+		}
+		for key, value := range torrent.Meta {
+			meta[key] = value
+		}
+		annotatedTorrents = append(annotatedTorrents, &AnnotatedTorrent{
+			Torrent:       torrent,
+			Site:          torrent.GetSite(),
+			TrackerDomain: strings.ToLower(torrent.TrackerDomain),
+			DisplayName:   name,
+			Meta:          meta,
+		})
+	}
+	return annotatedTorrents
+}
+
+// Estimate how long until client's disk is full at current download speed.
+// Return DISK_FULL_UNKNOWN if free space is unknown or download speed is zero.
+func TimeToDiskFull(status *Status) time.Duration {
+	if status == nil || status.FreeSpaceOnDisk < 0 || status.DownloadSpeed <= 0 {
+		return DISK_FULL_UNKNOWN
+	}
+	return time.Duration(float64(status.FreeSpaceOnDisk) / float64(status.DownloadSpeed) * float64(time.Second))
+}
+
+// Aggregated statistics of a list of torrents.
+type TorrentsSummary struct {
+	Count         int64
+	Size          int64
+	SizeCompleted int64
+	Downloaded    int64
+	Uploaded      int64
+	DownloadSpeed int64
+	UploadSpeed   int64
+	StateCounts   map[string]int64 // state => count of torrents in that state
+}
+
+func SummarizeTorrents(torrents []*Torrent) *TorrentsSummary {
+	summary := &TorrentsSummary{StateCounts: map[string]int64{}}
+	for _, torrent := range torrents {
+		summary.Count++
+		summary.Size += torrent.Size
+		summary.SizeCompleted += torrent.SizeCompleted
+		summary.Downloaded += torrent.Downloaded
+		summary.Uploaded += torrent.Uploaded
+		summary.DownloadSpeed += torrent.DownloadSpeed
+		summary.UploadSpeed += torrent.UploadSpeed
+		summary.StateCounts[torrent.State]++
+	}
+	return summary
+}
+
+// Return at most n torrents with highest upload speed, in descending order. Idle torrents are excluded.
+func TopUploadingTorrents(torrents []*Torrent, n int) []*Torrent {
+	var uploading []*Torrent
+	for _, torrent := range torrents {
+		if torrent.UploadSpeed > 0 {
+			uploading = append(uploading, torrent)
+		}
+	}
+	slices.SortStableFunc(uploading, func(a, b *Torrent) int {
+		return cmp.Compare(b.UploadSpeed, a.UploadSpeed)
+	})
+	if len(uploading) > n {
+		uploading = uploading[:n]
+	}
+	return uploading
+}
+
+// Average transfer rates (bytes/s) over an interval.
+type StatusRates struct {
+	DownloadSpeed int64
+	UploadSpeed   int64
+}
+
+// Compute average download / upload rates between two Status snapshots taken interval apart,
+// from the differences of AllTimeDownloaded / AllTimeUploaded. If the counters are unknown or interval <= 0,
+// fall back to the instantaneous speeds of curr. If a counter is reset (curr < prev), that rate is 0.
+func StatusDelta(prev, curr *Status, interval time.Duration) *StatusRates {
+	rate := func(prevCounter, currCounter, speed int64) int64 {
+		if interval <= 0 || prevCounter <= 0 || currCounter <= 0 {
+			return speed
+		}
+		if currCounter < prevCounter {
+			return 0
+		}
+		return int64(float64(currCounter-prevCounter) / interval.Seconds())
+	}
+	return &StatusRates{
+		DownloadSpeed: rate(prev.AllTimeDownloaded, curr.AllTimeDownloaded, curr.DownloadSpeed),
+		UploadSpeed:   rate(prev.AllTimeUploaded, curr.AllTimeUploaded, curr.UploadSpeed),
+	}
+}
+
+// Return seeding efficiency of each site: sum(Uploaded) / sum(Size) of it's torrents, i.e. uploaded bytes
+// per stored byte. Site of torrent is parsed from it's "site:" tag, torrents without it are ignored.
+// Sites which total size is 0 are not included.
+func SiteSeedingEfficiency(torrents []*Torrent) map[string]float64 {
+	uploaded := map[string]int64{}
+	size := map[string]int64{}
+	for _, torrent := range torrents {
+		site := torrent.GetSiteFromTag()
+		if site == "" {
+			continue
+		}
+		uploaded[site] += torrent.Uploaded
+		size[site] += torrent.Size
+	}
+	efficiency := map[string]float64{}
+	for site := range size {
+		if size[site] > 0 {
+			efficiency[site] = float64(uploaded[site]) / float64(size[site])
+		}
+	}
+	return efficiency
+}
+
+// Statistics of torrents ages (seconds since added).
+type AgeStats struct {
+	Count  int64
+	Min    int64
+	Max    int64
+	Median int64
+}
+
+// Return min / max / median ages of torrents per category. Uncategorized torrents are under "" key.
+// Torrents with unknown add time (Atime == 0) are excluded.
+func CategoryAgeStats(torrents []*Torrent) map[string]*AgeStats {
+	return categoryAgeStats(torrents, util.Now())
+}
+
+func categoryAgeStats(torrents []*Torrent, now int64) map[string]*AgeStats {
+	categoryAges := map[string][]int64{}
+	for _, torrent := range torrents {
+		if torrent.Atime == 0 {
+			continue
+		}
+		categoryAges[torrent.Category] = append(categoryAges[torrent.Category], max(now-torrent.Atime, 0))
+	}
+	stats := map[string]*AgeStats{}
+	for category, ages := range categoryAges {
+		slices.Sort(ages)
+		stats[category] = &AgeStats{
+			Count:  int64(len(ages)),
+			Min:    ages[0],
+			Max:    ages[len(ages)-1],
+			Median: median(ages),
+		}
+	}
+	return stats
+}
+
+// Return the median of sorted non-empty values. For even count, it's the mean of the middle two.
+func median(sortedValues []int64) int64 {
+	n := len(sortedValues)
+	if n%2 == 1 {
+		return sortedValues[n/2]
+	}
+	return (sortedValues[n/2-1] + sortedValues[n/2]) / 2
+}
+
+// Actions recommended by RecommendSeedingActions.
+const (
+	SEEDING_ACTION_KEEP   = "keep"
+	SEEDING_ACTION_PAUSE  = "pause"
+	SEEDING_ACTION_DELETE = "delete"
+)
+
+// Recommended action for a torrent, with a human readable reason.
+type SeedingAction struct {
+	Torrent *Torrent
+	Action  string // SEEDING_ACTION_*
+	Reason  string
+}
+
+// Thresholds used to recommend seeding actions. Availability of torrent is measured by it's Availability
+// if known (qb), otherwise by it's Seeders count.
+type SeedingPolicy struct {
+	LowAvailability  float64 // torrents with availability <= this are always kept
+	HighAvailability float64 // torrents with availability >= this are considered well seeded
+	LowSeeders       int64   // torrents with seeders <= this are always kept
+	HighSeeders      int64   // torrents with seeders >= this are considered well seeded
+	MinRatio         float64 // torrents with ratio >= this are considered "paid back"
+	MinAge           int64   // seconds since completed (or added), torrents older than this are considered old
+	LowFreeSpace     int64   // if client's free disk space is below this (bytes), disk is under pressure
+}
+
+// Policy used by RecommendSeedingActions. Can be overridden.
+var DefaultSeedingPolicy = &SeedingPolicy{
+	LowAvailability:  2,
+	HighAvailability: 10,
+	LowSeeders:       3,
+	HighSeeders:      20,
+	MinRatio:         2,
+	MinAge:           30 * 86400,
+	LowFreeSpace:     50 * 1024 * 1024 * 1024,
+}
+
+// Recommend keep / pause / delete actions for torrents using DefaultSeedingPolicy. Nothing is executed.
+func RecommendSeedingActions(torrents []*Torrent, status *Status) []*SeedingAction {
+	return DefaultSeedingPolicy.Recommend(torrents, status)
+}
+
+// Recommend an action for each torrent:
+// not completed, unknown or low availability: keep; high ratio, high availability and old: delete;
+// ratio reached while disk is under pressure: pause; otherwise keep.
+// Seeders <= 0 is treated as unknown, as many trackers do not report it. Ratio of torrents with zero Downloaded
+// (e.g. cross-seeded ones) is treated as unknown too, such torrents are always kept.
+func (policy *SeedingPolicy) Recommend(torrents []*Torrent, status *Status) []*SeedingAction {
+	return policy.recommend(torrents, status, util.Now())
+}
+
+func (policy *SeedingPolicy) recommend(torrents []*Torrent, status *Status, now int64) []*SeedingAction {
+	diskPressure := status != nil && status.FreeSpaceOnDisk >= 0 && status.FreeSpaceOnDisk < policy.LowFreeSpace
+	actions := make([]*SeedingAction, 0, len(torrents))
+	for _, torrent := range torrents {
+		action := &SeedingAction{Torrent: torrent, Action: SEEDING_ACTION_KEEP}
+		age := now - torrent.Atime
+		if torrent.Ctime > 0 {
+			age = now - torrent.Ctime
+		}
+		var lowAvailability, highAvailability bool
+		var availability string
+		if torrent.Availability >= 0 {
+			lowAvailability = torrent.Availability <= policy.LowAvailability
+			highAvailability = torrent.Availability >= policy.HighAvailability
+			availability = fmt.Sprintf("%.2f availability", torrent.Availability)
+		} else if torrent.Seeders > 0 {
+			lowAvailability = torrent.Seeders <= policy.LowSeeders
+			highAvailability = torrent.Seeders >= policy.HighSeeders
+			availability = fmt.Sprintf("%d seeders", torrent.Seeders)
+		}
+		switch {
+		case !torrent.IsComplete():
+			action.Reason = "not completed"
+		case availability == "":
+			action.Reason = "unknown availability"
+		case lowAvailability:
+			action.Reason = fmt.Sprintf("low availability (%s)", availability)
+		case torrent.Downloaded == 0:
+			action.Reason = "unknown ratio"
+		case torrent.Ratio >= policy.MinRatio && highAvailability && age >= policy.MinAge:
+			action.Action = SEEDING_ACTION_DELETE
+			action.Reason = fmt.Sprintf("ratio %.2f, %s, %s old", torrent.Ratio, availability, util.FormatDuration(age))
+		case diskPressure && torrent.Ratio >= policy.MinRatio:
+			action.Action = SEEDING_ACTION_PAUSE
+			action.Reason = fmt.Sprintf("low disk space, ratio %.2f", torrent.Ratio)
+		default:
+			action.Reason = "seeding"
+		}
+		actions = append(actions, action)
+	}
+	return actions
+}
+
+// Dedup keys of MergeClientTorrents.
+const (
+	MERGE_BY_INFOHASH = "infohash"
+	MERGE_BY_CONTENT  = "content" // by Torrent.ContentID
+)
+
+// A torrent held by one or more clients. See MergeClientTorrents.
+type MergedTorrent struct {
+	*Torrent          // copy of the torrent in the first holder client, with transfer stats summed across holders
+	Clients  []string // names of clients that hold the torrent
+}
+
+// Merge torrents lists of clients (client name => torrents) into one deduped list, e.g. for a unified view of
+// mirrored primary / backup clients. dedupBy is MERGE_BY_INFOHASH or MERGE_BY_CONTENT. In the latter case,
+// ContentID of all torrents must be already got (it's cached), otherwise an error is returned.
+// Downloaded, Uploaded, DownloadSpeed and UploadSpeed are summed across merged torrents and Ratio is re-computed.
+// Clients are processed in name order; result is in order of first appearance. Input torrents are not modified.
+func MergeClientTorrents(lists map[string][]*Torrent, dedupBy string) ([]*MergedTorrent, error) {
+	if dedupBy != MERGE_BY_INFOHASH && dedupBy != MERGE_BY_CONTENT {
+		return nil, fmt.Errorf("invalid dedupBy %q", dedupBy)
+	}
+	var result []*MergedTorrent
+	merged := map[string]*MergedTorrent{}
+	for _, clientName := range util.MapKeys(lists) {
+		for _, torrent := range lists[clientName] {
+			key := torrent.InfoHash
+			if dedupBy == MERGE_BY_CONTENT {
+				if torrent.contentId == "" {
+					return nil, fmt.Errorf("content id of torrent %s of client %s is unknown", torrent.InfoHash, clientName)
+				}
+				key = torrent.contentId
+			}
+			mergedTorrent := merged[key]
+			if mergedTorrent == nil {
+				torrentCopy := *torrent
+				mergedTorrent = &MergedTorrent{Torrent: &torrentCopy}
+				merged[key] = mergedTorrent
+				result = append(result, mergedTorrent)
+			} else {
+				mergedTorrent.Downloaded += torrent.Downloaded
+				mergedTorrent.Uploaded += torrent.Uploaded
+				mergedTorrent.DownloadSpeed += torrent.DownloadSpeed
+				mergedTorrent.UploadSpeed += torrent.UploadSpeed
+				// Also when merged torrents are in the same client (MERGE_BY_CONTENT)
+				if mergedTorrent.Downloaded > 0 {
+					mergedTorrent.Ratio = float64(mergedTorrent.Uploaded) / float64(mergedTorrent.Downloaded)
+				} else {
+					mergedTorrent.Ratio = INFINITE_RATIO
+				}
+			}
+			if !slices.Contains(mergedTorrent.Clients, clientName) {
+				mergedTorrent.Clients = append(mergedTorrent.Clients, clientName)
+			}
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,127 @@
+package client
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCategoryAgeStats(t *testing.T) {
+	now := int64(1000)
+	torrents := []*Torrent{
+		{Category: "odd", Atime: 900},
+		{Category: "odd", Atime: 700},
+		{Category: "odd", Atime: 800},
+		{Category: "even", Atime: 990},
+		{Category: "even", Atime: 960},
+		{Category: "even", Atime: 900},
+		{Category: "even", Atime: 980},
+		{Category: "even", Atime: 0},
+		{Category: "unknown", Atime: 0},
+	}
+	want := map[string]*AgeStats{
+		"odd":  {Count: 3, Min: 100, Max: 300, Median: 200},
+		"even": {Count: 4, Min: 10, Max: 100, Median: 30},
+	}
+	got := categoryAgeStats(torrents, now)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("categoryAgeStats() = %v, want %v", got, want)
+	}
+}
+
+func TestMergeClientTorrents(t *testing.T) {
+	lists := map[string][]*Torrent{
+		"primary": {
+			{InfoHash: "a", Downloaded: 100, Uploaded: 50, UploadSpeed: 1, contentId: "x"},
+			{InfoHash: "b", Uploaded: 10, contentId: "y"},
+		},
+		"backup": {
+			{InfoHash: "a", Downloaded: 100, Uploaded: 150, UploadSpeed: 2, contentId: "x"},
+			{InfoHash: "c", Uploaded: 20, contentId: "y"},
+		},
+	}
+
+	merged, err := MergeClientTorrents(lists, MERGE_BY_INFOHASH)
+	if err != nil {
+		t.Fatalf("MergeClientTorrents() error = %v", err)
+	}
+	got := map[string][]string{}
+	for _, torrent := range merged {
+		got[torrent.InfoHash] = torrent.Clients
+	}
+	want := map[string][]string{"a": {"backup", "primary"}, "b": {"primary"}, "c": {"backup"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MergeClientTorrents(infohash) clients = %v, want %v", got, want)
+	}
+	if merged[0].Uploaded != 200 || merged[0].UploadSpeed != 3 || merged[0].Ratio != 1 {
+		t.Errorf("MergeClientTorrents(infohash) stats = %+v", merged[0].Torrent)
+	}
+	if lists["backup"][0].Uploaded != 150 {
+		t.Errorf("MergeClientTorrents() modified input torrent")
+	}
+
+	merged, err = MergeClientTorrents(lists, MERGE_BY_CONTENT)
+	if err != nil {
+		t.Fatalf("MergeClientTorrents() error = %v", err)
+	}
+	if len(merged) != 2 || merged[1].Uploaded != 30 ||
+		!reflect.DeepEqual(merged[1].Clients, []string{"backup", "primary"}) {
+		t.Errorf("MergeClientTorrents(content) = %v", merged)
+	}
+
+	// same content in one client
+	sameClientLists := map[string][]*Torrent{
+		"primary": {
+			{InfoHash: "a", Downloaded: 100, Uploaded: 100, Ratio: 1, contentId: "x"},
+			{InfoHash: "b", Downloaded: 100, Uploaded: 300, Ratio: 3, contentId: "x"},
+		},
+	}
+	merged, err = MergeClientTorrents(sameClientLists, MERGE_BY_CONTENT)
+	if err != nil {
+		t.Fatalf("MergeClientTorrents() error = %v", err)
+	}
+	if len(merged) != 1 || merged[0].Uploaded != 400 || merged[0].Ratio != 2 {
+		t.Errorf("MergeClientTorrents(content) of same client = %v", merged)
+	}
+
+	lists["primary"][1].contentId = ""
+	if _, err = MergeClientTorrents(lists, MERGE_BY_CONTENT); err == nil {
+		t.Errorf("MergeClientTorrents(content) with unknown content id should fail")
+	}
+}
+
+func TestRecommendSeedingActions(t *testing.T) {
+	now := int64(100 * 86400)
+	old := now - 60*86400
+	torrents := []*Torrent{
+		{InfoHash: "incomplete", Size: 100, SizeCompleted: 50, Availability: -1, Seeders: 50},
+		{InfoHash: "unknown", Downloaded: 100, Ratio: 5, Ctime: old, Availability: -1, Seeders: 0},
+		{InfoHash: "unknown-seeders", Downloaded: 100, Ratio: 5, Ctime: old, Availability: -1, Seeders: -1},
+		{InfoHash: "few-seeders", Downloaded: 100, Ratio: 5, Ctime: old, Availability: -1, Seeders: 2},
+		{InfoHash: "many-seeders", Downloaded: 100, Ratio: 5, Ctime: old, Availability: -1, Seeders: 50},
+		{InfoHash: "low-availability", Downloaded: 100, Ratio: 5, Ctime: old, Availability: 1.5, Seeders: 50},
+		{InfoHash: "high-availability", Downloaded: 100, Ratio: 5, Ctime: old, Availability: 20, Seeders: 0},
+		{InfoHash: "young", Downloaded: 100, Ratio: 5, Ctime: now - 86400, Availability: 20},
+		{InfoHash: "low-ratio", Downloaded: 100, Ratio: 1, Ctime: old, Availability: 20},
+		{InfoHash: "xseed", Downloaded: 0, Ratio: INFINITE_RATIO, Ctime: old, Availability: 20},
+	}
+	want := map[string]string{
+		"incomplete":        SEEDING_ACTION_KEEP,
+		"unknown":           SEEDING_ACTION_KEEP,
+		"unknown-seeders":   SEEDING_ACTION_KEEP,
+		"few-seeders":       SEEDING_ACTION_KEEP,
+		"many-seeders":      SEEDING_ACTION_DELETE,
+		"low-availability":  SEEDING_ACTION_KEEP,
+		"high-availability": SEEDING_ACTION_DELETE,
+		"young":             SEEDING_ACTION_PAUSE,
+		"low-ratio":         SEEDING_ACTION_KEEP,
+		"xseed":             SEEDING_ACTION_KEEP,
+	}
+	status := &Status{FreeSpaceOnDisk: 0}
+	got := map[string]string{}
+	for _, action := range DefaultSeedingPolicy.recommend(torrents, status, now) {
+		got[action.Torrent.InfoHash] = action.Action
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("recommend() = %v, want %v", got, want)
+	}
+}
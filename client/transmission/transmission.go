@@ -14,6 +14,7 @@ import (
 	"reflect"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/ettle/strcase"
 	transmissionrpc "github.com/hekmon/transmissionrpc/v2"
@@ -21,6 +22,7 @@ import (
 	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/util"
+	"github.com/sagan/ptool/util/torrentutil"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -36,6 +38,7 @@ type Client struct {
 	sessionStats              *transmissionrpc.SessionStats
 	sessionArgs               *transmissionrpc.SessionArguments
 	freeSpace                 int64
+	diskTotal                 int64
 	unfinishedSize            int64
 	unfinishedDownloadingSize int64
 	contentPathTorrents       map[string][]*transmissionrpc.Torrent
@@ -127,9 +130,11 @@ func (trclient *Client) Sync(full bool) (err error) {
 		torrents, err = transmissionbt.TorrentGetAll(context.TODO())
 	} else {
 		torrents, err = transmissionbt.TorrentGet(context.TODO(), []string{
-			"activityDate", "addedDate", "doneDate", "downloadDir", "downloadedEver", "downloadLimit", "downloadLimited",
-			"hashString", "id", "labels", "name", "peersGettingFromUs", "peersSendingToUs", "percentDone", "rateDownload",
-			"rateUpload", "sizeWhenDone", "status", "trackers", "totalSize", "uploadedEver", "uploadLimit", "uploadLimited",
+			"activityDate", "addedDate", "comment", "doneDate", "downloadDir", "downloadedEver", "downloadLimit",
+			"downloadLimited", "hashString", "id", "labels", "name", "peersConnected", "peersGettingFromUs",
+			"peersSendingToUs", "percentDone", "pieceCount", "pieceSize", "queuePosition", "rateDownload",
+			"rateUpload", "sizeWhenDone", "status", "trackers", "totalSize", "uploadedEver", "uploadLimit",
+			"uploadLimited",
 		}, nil)
 	}
 
@@ -179,14 +184,18 @@ func (trclient *Client) syncMeta() error {
 	if err != nil {
 		return err
 	}
-	freeSpace, err := transmissionbt.FreeSpace(context.TODO(), *sessionArgs.DownloadDir)
+	freeSpace, err := transmissionbt.FreeSpaceDetails(context.TODO(), *sessionArgs.DownloadDir)
 	if err != nil {
 		return err
 	}
 	trclient.datatimeMeta = now
 	trclient.sessionStats = &sessionStats
 	trclient.sessionArgs = &sessionArgs
-	trclient.freeSpace = int64(freeSpace / 8) // tr freespace is in bits.
+	trclient.freeSpace = freeSpace.Size
+	trclient.diskTotal = freeSpace.TotalSize
+	if trclient.diskTotal <= 0 {
+		trclient.diskTotal = -1 // tr < 4.0 does not report it
+	}
 	return nil
 }
 
@@ -237,9 +246,16 @@ func (trclient *Client) GetTorrents(stateFilter string, category string, showAll
 
 func (trclient *Client) AddTorrent(torrentContent []byte, option *client.TorrentOption, meta map[string]int64) error {
 	transmissionbt := trclient.client
+	if option.SourceUrl != "" && !util.IsTorrentUrl(string(torrentContent)) {
+		var err error
+		if torrentContent, err = client.SetTorrentContentSourceUrl(torrentContent, option.SourceUrl); err != nil {
+			return err
+		}
+	}
 	var downloadDir *string
 	if option.SavePath != "" {
-		downloadDir = &option.SavePath
+		savePath := torrentutil.ResolveOptionSavePath(torrentContent, option)
+		downloadDir = &savePath
 	}
 	payload := transmissionrpc.TorrentAddPayload{
 		Paused:      &option.Pause,
@@ -252,6 +268,12 @@ func (trclient *Client) AddTorrent(torrentContent []byte, option *client.Torrent
 		torrentContentB64 := base64.StdEncoding.EncodeToString(torrentContent)
 		payload.MetaInfo = &torrentContentB64
 	}
+	if option.SkipChecking {
+		log.Warnf("transmission does not support skip checking, torrent will be checked after added")
+	}
+	if option.ContentLayout != "" && option.ContentLayout != "original" {
+		log.Warnf("transmission does not support content layout %q, original layout is used", option.ContentLayout)
+	}
 	// returned torrent will only have HashString, ID and Name fields set up.
 	torrent, err := transmissionbt.TorrentAdd(context.TODO(), payload)
 	if err != nil {
@@ -265,7 +287,7 @@ func (trclient *Client) AddTorrent(torrentContent []byte, option *client.Torrent
 		log.Tracef("rename tr torrent name=%s err=%v", name, err)
 	}
 
-	labels := util.CopySlice(option.Tags)
+	labels := util.CopySlice(client.GenerateAddTorrentTags(torrentContent, option.Tags))
 	if option.Category != "" && option.Category != constants.NONE {
 		// use label to simulate category
 		labels = append(labels, client.GenerateTorrentTagFromCategory(option.Category))
@@ -339,16 +361,19 @@ func (trclient *Client) ModifyTorrent(infoHash string, option *client.TorrentOpt
 		labels = append(labels, option.Tags...)
 		if len(meta) > 0 {
 			for name, value := range meta {
-				labels = append(labels, client.GenerateTorrentTagFromMetadata(name, value))
+				if value != 0 {
+					labels = append(labels, client.GenerateTorrentTagFromMetadata(name, value))
+				}
 			}
 		} else if len(torrent.Meta) > 0 {
 			for name, value := range torrent.Meta {
 				labels = append(labels, client.GenerateTorrentTagFromMetadata(name, value))
 			}
 		}
-		if len(labels) > 0 || len(option.RemoveTags) > 0 {
+		if len(labels) > 0 || len(option.RemoveTags) > 0 || len(meta) > 0 {
 			for _, tag := range torrent.Tags {
-				if !slices.Contains(option.RemoveTags, tag) {
+				// category & meta labels are already re-generated above.
+				if !client.IsSubstituteTag(tag) && !slices.Contains(option.RemoveTags, tag) {
 					labels = append(labels, tag)
 				}
 			}
@@ -356,35 +381,11 @@ func (trclient *Client) ModifyTorrent(infoHash string, option *client.TorrentOpt
 		}
 	}
 
-	if option.DownloadSpeedLimit != 0 && option.DownloadSpeedLimit != torrent.DownloadSpeedLimit {
-		downloadLimited := true
-		downloadLimit := int64(0)
-		if option.DownloadSpeedLimit > 0 {
-			downloadLimit := option.DownloadSpeedLimit / 1024
-			if downloadLimit == 0 {
-				downloadLimit = 1
-			}
-		} else {
-			downloadLimited = false
-		}
-
-		payload.DownloadLimited = &downloadLimited
-		payload.DownloadLimit = &downloadLimit
+	if option.DownloadSpeedLimit != 0 && max(option.DownloadSpeedLimit, -1) != torrent.DownloadSpeedLimit {
+		payload.DownloadLimited, payload.DownloadLimit = trSpeedLimit(option.DownloadSpeedLimit)
 	}
-	if option.UploadSpeedLimit != 0 && option.UploadSpeedLimit != torrent.UploadedSpeedLimit {
-		uploadLimited := true
-		uploadLimit := int64(0)
-		if option.UploadSpeedLimit > 0 {
-			uploadLimit = option.UploadSpeedLimit / 1024
-			if uploadLimit == 0 {
-				uploadLimit = 1
-			}
-		} else {
-			uploadLimited = false
-		}
-
-		payload.UploadLimited = &uploadLimited
-		payload.UploadLimit = &uploadLimit
+	if option.UploadSpeedLimit != 0 && max(option.UploadSpeedLimit, -1) != torrent.UploadSpeedLimit {
+		payload.UploadLimited, payload.UploadLimit = trSpeedLimit(option.UploadSpeedLimit)
 	}
 
 	if option.SavePath != "" {
@@ -420,6 +421,41 @@ func (trclient *Client) DeleteTorrents(infoHashes []string, deleteFiles bool) (e
 	return
 }
 
+func (trclient *Client) SetTorrentsSpeedLimit(infoHashes []string,
+	downloadSpeedLimit int64, uploadSpeedLimit int64) error {
+	if len(infoHashes) == 0 || (downloadSpeedLimit == 0 && uploadSpeedLimit == 0) {
+		return nil
+	}
+	if err := trclient.Sync(false); err != nil {
+		return err
+	}
+	payload := transmissionrpc.TorrentSetPayload{
+		IDs: trclient.getIds(infoHashes),
+	}
+	if downloadSpeedLimit != 0 {
+		payload.DownloadLimited, payload.DownloadLimit = trSpeedLimit(downloadSpeedLimit)
+	}
+	if uploadSpeedLimit != 0 {
+		payload.UploadLimited, payload.UploadLimit = trSpeedLimit(uploadSpeedLimit)
+	}
+	return trclient.client.TorrentSet(context.TODO(), payload)
+}
+
+func (trclient *Client) SetTorrentPriority(infoHash string, priority int64) error {
+	if priority <= 0 {
+		return fmt.Errorf("invalid priority %d", priority)
+	}
+	trtorrent, err := trclient.getTorrent(infoHash, false)
+	if err != nil {
+		return err
+	}
+	queuePosition := priority - 1
+	return trclient.client.TorrentSet(context.TODO(), transmissionrpc.TorrentSetPayload{
+		IDs:           []int64{*trtorrent.ID},
+		QueuePosition: &queuePosition,
+	})
+}
+
 func (trclient *Client) PauseTorrents(infoHashes []string) error {
 	return trclient.client.TorrentStopHashes(context.TODO(), infoHashes)
 }
@@ -546,11 +582,20 @@ func (trclient *Client) DeleteTags(tags ...string) error {
 }
 
 func (trclient *Client) MakeCategory(category string, savePath string) error {
-	return fmt.Errorf("unsupported")
+	return client.ErrUnsupported
+}
+
+// Transmission RPC (torrent-set) does not support editing comment.
+func (trclient *Client) SetTorrentsComment(infoHashes []string, comment string) error {
+	return client.ErrUnsupported
+}
+
+func (trclient *Client) SetTorrentsAutoManagement(infoHashes []string, enabled bool) error {
+	return client.ErrUnsupported
 }
 
 func (trclient *Client) DeleteCategories(categories []string) error {
-	return fmt.Errorf("unsupported")
+	return client.ErrUnsupported
 }
 
 func (trclient *Client) GetCategories() ([]*client.TorrentCategory, error) {
@@ -572,6 +617,23 @@ func (trclient *Client) GetCategories() ([]*client.TorrentCategory, error) {
 	return cats, nil
 }
 
+// Category in tr is simulated using label, which does not have a save path.
+func (trclient *Client) GetCategorySavePath(category string) (string, error) {
+	return "", client.ErrUnsupported
+}
+
+func (trclient *Client) GetCategorySavePaths() (map[string]string, error) {
+	categories, err := trclient.GetCategories()
+	if err != nil {
+		return nil, err
+	}
+	savePaths := map[string]string{}
+	for _, category := range categories {
+		savePaths[category.Name] = ""
+	}
+	return savePaths, nil
+}
+
 func (trclient *Client) SetTorrentsCatetory(infoHashes []string, category string) error {
 	for _, infoHash := range infoHashes {
 		trclient.ModifyTorrent(infoHash, &client.TorrentOption{
@@ -657,8 +719,11 @@ func (trclient *Client) GetStatus() (*client.Status, error) {
 		DownloadSpeedLimit:        downloadSpeedLimit,
 		UploadSpeedLimit:          uploadSpeedLimit,
 		FreeSpaceOnDisk:           trclient.freeSpace,
+		DiskTotal:                 trclient.diskTotal,
 		UnfinishedSize:            trclient.unfinishedSize,
 		UnfinishedDownloadingSize: trclient.unfinishedDownloadingSize,
+		AllTimeDownloaded:         trclient.sessionStats.CumulativeStats.DownloadedBytes,
+		AllTimeUploaded:           trclient.sessionStats.CumulativeStats.UploadedBytes,
 	}, nil
 }
 
@@ -784,6 +849,26 @@ func (trclient *Client) GetTorrentTrackers(infoHash string) (client.TorrentTrack
 	if err != nil {
 		return nil, err
 	}
+	return tr2Trackers(torrent), nil
+}
+
+func (trclient *Client) GetTorrentsTrackers(infoHashes []string) (map[string]client.TorrentTrackers, error) {
+	torrentsTrackers := map[string]client.TorrentTrackers{}
+	if len(infoHashes) == 0 {
+		return torrentsTrackers, nil
+	}
+	torrents, err := trclient.client.TorrentGetAllForHashes(context.TODO(), infoHashes)
+	if err != nil {
+		return nil, err
+	}
+	for i := range torrents {
+		torrentsTrackers[*torrents[i].HashString] = tr2Trackers(&torrents[i])
+	}
+	return torrentsTrackers, nil
+}
+
+// Convert trackerStats of a full torrent info to trackers.
+func tr2Trackers(torrent *transmissionrpc.Torrent) client.TorrentTrackers {
 	trackers := []client.TorrentTracker{}
 	for _, trackerStat := range torrent.TrackerStats {
 		status := "unknown"
@@ -797,12 +882,16 @@ func (trclient *Client) GetTorrentTrackers(infoHash string) (client.TorrentTrack
 			msg = trackerStat.LastScrapeResult
 		}
 		trackers = append(trackers, client.TorrentTracker{
-			Url:    trackerStat.Announce,
-			Status: status,
-			Msg:    msg,
+			Url:              trackerStat.Announce,
+			Status:           status,
+			Msg:              msg,
+			Seeders:          trackerStat.SeederCount,
+			Leechers:         trackerStat.LeecherCount,
+			Peers:            trackerStat.LastAnnouncePeerCount,
+			NextAnnounceTime: max(trackerStat.NextAnnounceTime.Unix(), 0),
 		})
 	}
-	return trackers, nil
+	return trackers
 }
 
 func (trclient *Client) EditTorrentTracker(infoHash string, oldTracker string, newTracker string, replaceHost bool) error {
@@ -908,10 +997,154 @@ func (trclient *Client) SetFilePriority(infoHash string, fileIndexes []int64, pr
 	return ErrNotImplemented
 }
 
+// Send an empty session-set request, which makes transmission-daemon write it's settings.json.
+// It does not save torrents resume files, transmission has no RPC for that.
+func (trclient *Client) FlushState() error {
+	return trclient.client.SessionArgumentsSet(context.TODO(), transmissionrpc.SessionArguments{})
+}
+
+// Transmission does not support fully disabling encryption, "disable" maps to "tolerated",
+// which prefers unencrypted connections but still accepts encrypted ones.
+var trEncryptionModes = map[string]string{
+	"prefer":  "preferred",
+	"require": "required",
+	"disable": "tolerated",
+}
+
+func (trclient *Client) SetEncryptionMode(mode string) error {
+	trmode, ok := trEncryptionModes[mode]
+	if !ok {
+		return fmt.Errorf("invalid encryption mode %q", mode)
+	}
+	trclient.datatimeMeta = 0
+	return trclient.client.SessionArgumentsSet(context.TODO(), transmissionrpc.SessionArguments{
+		Encryption: &trmode,
+	})
+}
+
+func (trclient *Client) GetEncryptionMode() (string, error) {
+	if err := trclient.syncMeta(); err != nil {
+		return "", err
+	}
+	if trclient.sessionArgs.Encryption != nil {
+		for mode, trmode := range trEncryptionModes {
+			if trmode == *trclient.sessionArgs.Encryption {
+				return mode, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("unknown tr encryption value")
+}
+
+func (trclient *Client) GetBannedPeers() ([]string, error) {
+	return nil, client.ErrUnsupported
+}
+
+func (trclient *Client) BanPeers(peers []string) error {
+	return client.ErrUnsupported
+}
+
+func (trclient *Client) GetLog(lastKnownId int64) ([]*client.LogEntry, int64, error) {
+	return nil, lastKnownId, client.ErrUnsupported
+}
+
+// tr calls LSD as "LPD" (Local Peer Discovery).
+func (trclient *Client) GetGlobalPeerDiscovery() (dht bool, pex bool, lsd bool, err error) {
+	if err = trclient.syncMeta(); err != nil {
+		return false, false, false, err
+	}
+	sessionArgs := trclient.sessionArgs
+	if sessionArgs.DHTEnabled == nil || sessionArgs.PEXEnabled == nil || sessionArgs.LPDEnabled == nil {
+		return false, false, false, fmt.Errorf("tr session does not have peer discovery args")
+	}
+	return *sessionArgs.DHTEnabled, *sessionArgs.PEXEnabled, *sessionArgs.LPDEnabled, nil
+}
+
+func (trclient *Client) SetGlobalPeerDiscovery(dht bool, pex bool, lsd bool) error {
+	trclient.datatimeMeta = 0
+	return trclient.client.SessionArgumentsSet(context.TODO(), transmissionrpc.SessionArguments{
+		DHTEnabled: &dht,
+		PEXEnabled: &pex,
+		LPDEnabled: &lsd,
+	})
+}
+
+// tr alt-speed-time-day is a bitmask of days, Sunday = 1, Monday = 2, ..., Saturday = 64.
+func (trclient *Client) GetScheduler() (*client.SchedulerConfig, error) {
+	if err := trclient.syncMeta(); err != nil {
+		return nil, err
+	}
+	sessionArgs := trclient.sessionArgs
+	if sessionArgs.AltSpeedTimeEnabled == nil || sessionArgs.AltSpeedTimeBegin == nil ||
+		sessionArgs.AltSpeedTimeEnd == nil || sessionArgs.AltSpeedTimeDay == nil {
+		return nil, fmt.Errorf("tr session does not have alt speed time args")
+	}
+	scheduler := &client.SchedulerConfig{
+		Enabled: *sessionArgs.AltSpeedTimeEnabled,
+		From:    *sessionArgs.AltSpeedTimeBegin,
+		To:      *sessionArgs.AltSpeedTimeEnd,
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if *sessionArgs.AltSpeedTimeDay&(1<<day) != 0 {
+			scheduler.Days = append(scheduler.Days, day)
+		}
+	}
+	return scheduler, nil
+}
+
+func (trclient *Client) SetScheduler(scheduler *client.SchedulerConfig) error {
+	days := int64(0)
+	for _, day := range scheduler.Days {
+		days |= 1 << day
+	}
+	if days == 0 {
+		days = 127
+	}
+	trclient.datatimeMeta = 0
+	return trclient.client.SessionArgumentsSet(context.TODO(), transmissionrpc.SessionArguments{
+		AltSpeedTimeEnabled: &scheduler.Enabled,
+		AltSpeedTimeBegin:   &scheduler.From,
+		AltSpeedTimeEnd:     &scheduler.To,
+		AltSpeedTimeDay:     &days,
+	})
+}
+
+// tr only has per-torrent upload slots.
+func (trclient *Client) GetGlobalMaxUploads() (int, error) {
+	return 0, client.ErrUnsupported
+}
+
+func (trclient *Client) SetGlobalMaxUploads(n int) error {
+	return client.ErrUnsupported
+}
+
+// Transmission always keeps torrent's original content layout.
+func (trclient *Client) GetAddDefaults() (*client.AddDefaults, error) {
+	if err := trclient.syncMeta(); err != nil {
+		return nil, err
+	}
+	return &client.AddDefaults{
+		SavePath:      *trclient.sessionArgs.DownloadDir,
+		Paused:        trclient.sessionArgs.StartAddedTorrents != nil && !*trclient.sessionArgs.StartAddedTorrents,
+		ContentLayout: "original",
+	}, nil
+}
+
 func (trclient *Client) Close() {
 	trclient.PurgeCache()
 }
 
+func validateConfig(clientConfig *config.ClientConfigStruct) error {
+	urlObj, err := url.Parse(clientConfig.Url)
+	if err != nil || (urlObj.Scheme != "http" && urlObj.Scheme != "https") || urlObj.Hostname() == "" {
+		return fmt.Errorf("invalid tr url: %q", clientConfig.Url)
+	}
+	if port := urlObj.Port(); port != "" && util.ParseInt(port) <= 0 {
+		return fmt.Errorf("invalid tr url port: %q", clientConfig.Url)
+	}
+	return nil
+}
+
 func NewClient(name string, clientConfig *config.ClientConfigStruct, config *config.ConfigStruct) (
 	client.Client, error) {
 	urlObj, err := url.Parse(clientConfig.Url)
@@ -958,11 +1191,13 @@ func NewClient(name string, clientConfig *config.ClientConfigStruct, config *con
 
 func init() {
 	client.Register(&client.RegInfo{
-		Name:    "transmission",
-		Creator: NewClient,
+		Name:      "transmission",
+		Creator:   NewClient,
+		Validator: validateConfig,
 	})
 }
 
+// tr does not report a "moving" status when relocating torrent data (torrent-set-location).
 func tr2State(trtorrent *transmissionrpc.Torrent) string {
 	switch *trtorrent.Status {
 	case 0: // TorrentStatusStopped
@@ -992,6 +1227,16 @@ func tr2State(trtorrent *transmissionrpc.Torrent) string {
 	}
 }
 
+// Convert speed limit (bytes/s, < 0 means no limit) to tr limited flag & limit (KiB/s).
+func trSpeedLimit(speedLimit int64) (limited *bool, limit *int64) {
+	trLimited := speedLimit > 0
+	trLimit := int64(0)
+	if trLimited {
+		trLimit = max(speedLimit/1024, 1)
+	}
+	return &trLimited, &trLimit
+}
+
 func getContentPath(trtorrent *transmissionrpc.Torrent) string {
 	sep := "/"
 	if strings.Contains(*trtorrent.DownloadDir, `\`) {
@@ -1001,18 +1246,47 @@ func getContentPath(trtorrent *transmissionrpc.Torrent) string {
 }
 
 func tr2Torrent(trtorrent *transmissionrpc.Torrent) *client.Torrent {
-	uploadSpeedLimit := int64(0)
-	downloadSpeedLimit := int64(0)
+	uploadSpeedLimit := int64(-1)
+	downloadSpeedLimit := int64(-1)
 	if *trtorrent.UploadLimited {
 		uploadSpeedLimit = *trtorrent.UploadLimit * 1024
 	}
 	if *trtorrent.DownloadLimited {
 		downloadSpeedLimit = *trtorrent.DownloadLimit * 1024
 	}
+	ratio := client.INFINITE_RATIO
+	if *trtorrent.DownloadedEver > 0 {
+		ratio = float64(*trtorrent.UploadedEver) / float64(*trtorrent.DownloadedEver)
+	}
+	comment := ""
+	if trtorrent.Comment != nil {
+		comment = *trtorrent.Comment
+	}
+	pieceSize := int64(0)
+	pieceCount := int64(0)
+	if trtorrent.PieceSize != nil && trtorrent.PieceCount != nil {
+		pieceSize = int64(*trtorrent.PieceSize / 8)
+		pieceCount = *trtorrent.PieceCount
+	}
+	priority := int64(0)
+	if trtorrent.QueuePosition != nil {
+		priority = *trtorrent.QueuePosition + 1 // tr queue position is 0-based
+	}
 	tracker := ""
 	if len(trtorrent.Trackers) > 0 {
 		tracker = trtorrent.Trackers[0].Announce
 	}
+	// Files are only present in full info.
+	filesComplete := int64(0)
+	for _, file := range trtorrent.Files {
+		if file.BytesCompleted == file.Length {
+			filesComplete++
+		}
+	}
+	connectedPeers := int64(0)
+	if trtorrent.PeersConnected != nil {
+		connectedPeers = *trtorrent.PeersConnected
+	}
 	torrent := &client.Torrent{
 		InfoHash:           *trtorrent.HashString,
 		Name:               *trtorrent.Name,
@@ -1029,17 +1303,29 @@ func tr2Torrent(trtorrent *transmissionrpc.Torrent) *client.Torrent {
 		DownloadSpeedLimit: downloadSpeedLimit,
 		Uploaded:           *trtorrent.UploadedEver,
 		UploadSpeed:        *trtorrent.RateUpload,
-		UploadedSpeedLimit: uploadSpeedLimit,
+		UploadSpeedLimit:   uploadSpeedLimit,
 		Category:           "",
 		SavePath:           *trtorrent.DownloadDir,
 		ContentPath:        getContentPath(trtorrent),
+		DownloadPath:       getContentPath(trtorrent), // tr does not report per-torrent incomplete-dir location
 		Tags:               trtorrent.Labels,
 		Seeders:            *trtorrent.PeersSendingToUs, // it's meaning is inconsistent with qb for now
 		Size:               int64(*trtorrent.SizeWhenDone / 8),
 		SizeCompleted:      int64(float64(*trtorrent.SizeWhenDone) * *trtorrent.PercentDone / 8),
 		SizeTotal:          int64(*trtorrent.TotalSize / 8),
 		Leechers:           *trtorrent.PeersGettingFromUs, // it's meaning is inconsistent with qb for now
+		Ratio:              ratio,
+		PieceSize:          pieceSize,
+		PieceCount:         pieceCount,
+		FilesTotal:         int64(len(trtorrent.Files)),
+		FilesComplete:      filesComplete,
+		Priority:           priority,
+		ConnectedPeers:     connectedPeers,
+		Availability:       -1, // tr does not report it
+		NextAnnounceTime:   trNextAnnounceTime(trtorrent),
 		Meta:               nil,
+		Comment:            comment,
+		SourceUrl:          client.ParseSourceUrlFromComment(comment),
 	}
 	torrent.Meta = torrent.GetMetadataFromTags()
 	torrent.Category = torrent.GetCategoryFromTag()
@@ -1047,6 +1333,17 @@ func tr2Torrent(trtorrent *transmissionrpc.Torrent) *client.Torrent {
 	return torrent
 }
 
+// Return the earliest next announce time of trackers. trackerStats is only available in full torrent info
+// (getTorrent(infoHash, true)), return 0 if it's absent.
+func trNextAnnounceTime(trtorrent *transmissionrpc.Torrent) (nextAnnounceTime int64) {
+	for _, trackerStat := range trtorrent.TrackerStats {
+		if ts := trackerStat.NextAnnounceTime.Unix(); ts > 0 && (nextAnnounceTime == 0 || ts < nextAnnounceTime) {
+			nextAnnounceTime = ts
+		}
+	}
+	return nextAnnounceTime
+}
+
 var (
 	_ client.Client = (*Client)(nil)
 )
@@ -44,12 +44,14 @@ import (
 	_ "github.com/sagan/ptool/cmd/resume"
 	_ "github.com/sagan/ptool/cmd/run"
 	_ "github.com/sagan/ptool/cmd/search"
+	_ "github.com/sagan/ptool/cmd/seedadvice"
 	_ "github.com/sagan/ptool/cmd/setcategory"
 	_ "github.com/sagan/ptool/cmd/setsavepath"
 	_ "github.com/sagan/ptool/cmd/shell"
 	_ "github.com/sagan/ptool/cmd/show"
 	_ "github.com/sagan/ptool/cmd/sites/all"
 	_ "github.com/sagan/ptool/cmd/skipchecking"
+	_ "github.com/sagan/ptool/cmd/speed"
 	_ "github.com/sagan/ptool/cmd/statscmd"
 	_ "github.com/sagan/ptool/cmd/status"
 	_ "github.com/sagan/ptool/cmd/tidyup"
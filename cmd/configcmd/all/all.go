@@ -2,6 +2,7 @@ package all
 
 import (
 	_ "github.com/sagan/ptool/cmd/configcmd"
+	_ "github.com/sagan/ptool/cmd/configcmd/check"
 	_ "github.com/sagan/ptool/cmd/configcmd/create"
 	_ "github.com/sagan/ptool/cmd/configcmd/example"
 	_ "github.com/sagan/ptool/cmd/configcmd/show"
@@ -0,0 +1,38 @@
+package check
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd/configcmd"
+	"github.com/sagan/ptool/config"
+)
+
+var command = &cobra.Command{
+	Use:   "check",
+	Short: "Check config file for mistakes.",
+	Long: `Check config file for mistakes.
+Currently it checks all clients: name must be non-empty and unique, type must be supported,
+and url must be valid. It does NOT make any network request.
+It exits with error if any problem is found, so it's suitable to be run before scheduled tasks.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(0), cobra.OnlyValidArgs),
+	RunE: check,
+}
+
+func init() {
+	configcmd.Command.AddCommand(command)
+}
+
+func check(cmd *cobra.Command, args []string) error {
+	errs := client.ValidateAllClients(config.Get())
+	for _, err := range errs {
+		fmt.Printf("✕ %v\n", err)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%d problems found", len(errs))
+	}
+	fmt.Printf("✓ No problem found\n")
+	return nil
+}
@@ -15,7 +15,7 @@ import (
 )
 
 var command = &cobra.Command{
-	Use:         "delete {client} [--category category] [--tag tag] [--filter filter] [infoHash]...",
+	Use:         "delete {client} [--category category] [--tag tag] [--filter filter] [--query query] [infoHash]...",
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "delete"},
 	Aliases:     []string{"del", "rm"},
 	Short:       "Delete torrents from client.",
@@ -36,6 +36,7 @@ var (
 	category          = ""
 	tag               = ""
 	tracker           = ""
+	query             = ""
 	minTorrentSizeStr = ""
 	maxTorrentSizeStr = ""
 )
@@ -51,6 +52,7 @@ func init() {
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
 	command.Flags().StringVarP(&tracker, "tracker", "", "", constants.HELP_ARG_TRACKER)
+	command.Flags().StringVarP(&query, "query", "", "", constants.HELP_ARG_QUERY)
 	command.Flags().StringVarP(&minTorrentSizeStr, "min-torrent-size", "", "-1", constants.HELP_ARG_MIN_TORRENT_SIZE)
 	command.Flags().StringVarP(&maxTorrentSizeStr, "max-torrent-size", "", "-1", constants.HELP_ARG_MAX_TORRENT_SIZE)
 	cmd.RootCmd.AddCommand(command)
@@ -60,6 +62,13 @@ func delete(cmd *cobra.Command, args []string) error {
 	if preserve && preserveXseed {
 		return fmt.Errorf("--preserve and --preserve-if-xseed-exist flags are NOT compatible")
 	}
+	var queryFilter *client.TorrentFilter
+	if query != "" {
+		var err error
+		if queryFilter, err = client.GetSavedQueryFilter(query); err != nil {
+			return err
+		}
+	}
 	clientName := args[0]
 	infoHashes := args[1:]
 	clientInstance, err := client.CreateClient(clientName)
@@ -69,7 +78,8 @@ func delete(cmd *cobra.Command, args []string) error {
 	minTorrentSize, _ := util.RAMInBytes(minTorrentSizeStr)
 	maxTorrentSize, _ := util.RAMInBytes(maxTorrentSizeStr)
 	infohashesOnly := true
-	if category != "" || tag != "" || filter != "" || tracker != "" || minTorrentSize >= 0 || maxTorrentSize >= 0 {
+	if category != "" || tag != "" || filter != "" || tracker != "" || query != "" ||
+		minTorrentSize >= 0 || maxTorrentSize >= 0 {
 		infohashesOnly = false
 	} else {
 		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
@@ -111,6 +121,9 @@ func delete(cmd *cobra.Command, args []string) error {
 			return true
 		})
 	}
+	if queryFilter != nil {
+		torrents = client.FilterTorrents(torrents, queryFilter)
+	}
 	// if preserve-xseed flag is set, the torrents which contains other-not-delete xseed torrents
 	var torrentsWithXseed []*client.Torrent
 	if preserveXseed {
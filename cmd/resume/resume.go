@@ -8,11 +8,12 @@ import (
 	"github.com/sagan/ptool/client"
 	"github.com/sagan/ptool/cmd"
 	"github.com/sagan/ptool/constants"
+	"github.com/sagan/ptool/util"
 	"github.com/sagan/ptool/util/helper"
 )
 
 var command = &cobra.Command{
-	Use:         "resume {client} [--category category] [--tag tag] [--filter filter] [infoHash]...",
+	Use:         "resume {client} [--category category] [--tag tag] [--filter filter] [--query query] [infoHash]...",
 	Annotations: map[string]string{"cobra-prompt-dynamic-suggestions": "resume"},
 	Aliases:     []string{"start"},
 	Short:       "Resume torrents of client.",
@@ -26,19 +27,28 @@ var (
 	category = ""
 	tag      = ""
 	filter   = ""
+	query    = ""
 )
 
 func init() {
 	command.Flags().StringVarP(&filter, "filter", "", "", constants.HELP_ARG_FILTER_TORRENT)
 	command.Flags().StringVarP(&category, "category", "", "", constants.HELP_ARG_CATEGORY)
 	command.Flags().StringVarP(&tag, "tag", "", "", constants.HELP_ARG_TAG)
+	command.Flags().StringVarP(&query, "query", "", "", constants.HELP_ARG_QUERY)
 	cmd.RootCmd.AddCommand(command)
 }
 
 func resume(cmd *cobra.Command, args []string) error {
 	clientName := args[0]
 	infoHashes := args[1:]
-	if category == "" && tag == "" && filter == "" {
+	var queryFilter *client.TorrentFilter
+	if query != "" {
+		var err error
+		if queryFilter, err = client.GetSavedQueryFilter(query); err != nil {
+			return err
+		}
+	}
+	if category == "" && tag == "" && filter == "" && query == "" {
 		if _infoHashes, err := helper.ParseInfoHashesFromArgs(infoHashes); err != nil {
 			return err
 		} else {
@@ -50,8 +60,17 @@ func resume(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
-	infoHashes, err = client.SelectTorrents(clientInstance, category, tag, filter, infoHashes...)
-	if err != nil {
+	if queryFilter != nil {
+		torrents, err := client.QueryTorrents(clientInstance, category, tag, filter, infoHashes...)
+		if err != nil {
+			return err
+		}
+		infoHashes = util.Map(client.FilterTorrents(torrents, queryFilter),
+			func(t *client.Torrent) string { return t.InfoHash })
+		if infoHashes == nil {
+			infoHashes = []string{} // nil means all torrents
+		}
+	} else if infoHashes, err = client.SelectTorrents(clientInstance, category, tag, filter, infoHashes...); err != nil {
 		return err
 	}
 	if infoHashes == nil {
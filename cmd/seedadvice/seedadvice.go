@@ -0,0 +1,80 @@
+package seedadvice
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+	"github.com/sagan/ptool/util"
+)
+
+var command = &cobra.Command{
+	Use:   "seedadvice {client}",
+	Short: "Recommend keep / pause / delete actions for torrents of client.",
+	Long: `Recommend keep / pause / delete actions for torrents of client.
+It's advisory only and does NOT modify any torrent.
+
+Policy:
+- Not completed or low availability (seeders <= --low-seeders) torrents: keep.
+- High ratio (>= --min-ratio), high availability (seeders >= --high-seeders) and old (>= --min-age) torrents: delete.
+- Torrents with ratio >= --min-ratio while client's free disk space is below --low-free-space: pause.
+- Other torrents: keep.`,
+	Args: cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	RunE: seedadvice,
+}
+
+var (
+	showAll      = false
+	category     = ""
+	lowFreeSpace = ""
+	minAge       = ""
+	policy       = *client.DefaultSeedingPolicy
+)
+
+func init() {
+	command.Flags().BoolVarP(&showAll, "all", "a", false, `Also show torrents recommended to keep`)
+	command.Flags().StringVarP(&category, "category", "", "", `Only check torrents of this category`)
+	command.Flags().Int64VarP(&policy.LowSeeders, "low-seeders", "", policy.LowSeeders,
+		`Torrents with seeders <= this are always kept`)
+	command.Flags().Int64VarP(&policy.HighSeeders, "high-seeders", "", policy.HighSeeders,
+		`Torrents with seeders >= this are considered well seeded`)
+	command.Flags().Float64VarP(&policy.MinRatio, "min-ratio", "", policy.MinRatio,
+		`Torrents with ratio >= this are considered paid back`)
+	command.Flags().StringVarP(&minAge, "min-age", "", "30d",
+		`Torrents completed longer than this are considered old`)
+	command.Flags().StringVarP(&lowFreeSpace, "low-free-space", "", "50GiB",
+		`If client's free disk space is below this, disk is considered under pressure`)
+	cmd.RootCmd.AddCommand(command)
+}
+
+func seedadvice(cmd *cobra.Command, args []string) (err error) {
+	if policy.MinAge, err = util.ParseTimeDuration(minAge); err != nil {
+		return fmt.Errorf("invalid min-age: %w", err)
+	}
+	if policy.LowFreeSpace, err = util.RAMInBytes(lowFreeSpace); err != nil {
+		return fmt.Errorf("invalid low-free-space: %w", err)
+	}
+	clientInstance, err := client.CreateClient(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+	status, err := clientInstance.GetStatus()
+	if err != nil {
+		return fmt.Errorf("failed to get client status: %w", err)
+	}
+	torrents, err := clientInstance.GetTorrents("", category, true)
+	if err != nil {
+		return fmt.Errorf("failed to get client torrents: %w", err)
+	}
+	fmt.Printf("%-6s  %-40s  %-30s  %s\n", "Action", "InfoHash", "Name", "Reason")
+	for _, action := range policy.Recommend(torrents, status) {
+		if action.Action == client.SEEDING_ACTION_KEEP && !showAll {
+			continue
+		}
+		fmt.Printf("%-6s  %-40s  %-30s  %s\n", action.Action, action.Torrent.InfoHash,
+			util.First(util.StringPrefixInWidth(action.Torrent.Name, 30)), action.Reason)
+	}
+	return nil
+}
@@ -49,6 +49,8 @@ The "State" field displays some icon texts:
 
 Specially, if all args is an (1) single info-hash, it displays the details of that torrent instead of the list.
 
+If "--show-ratio" flag is set, it also displays the share ratio of each torrent ("∞" if nothing downloaded).
+
 If "--json" flag is set, it prints torrents info in json (array) format.
 
 You can also customize the output format of each torrent using "--format string" flag.
@@ -57,31 +59,45 @@ The data passed to the template is the "client.Torrent" struct:
 // https://github.com/sagan/ptool/blob/master/client/client.go
 type Torrent struct {
 	InfoHash           string
+	InfoHashV1         string // v1 info-hash. "" if unknown or it's a pure v2 torrent
+	InfoHashV2         string // v2 info-hash. "" if unknown or it's a v1 only torrent
 	Name               string
 	TrackerDomain      string // e.g. tracker.m-team.cc
 	TrackerBaseDomain  string // e.g. m-team.cc
 	Tracker            string
-	State              string // simplified state: seeding|downloading|completed|paused|checking|error|unknown
-	LowLevelState      string // original state value returned by bt client
+	State              string // simplified state: seeding|downloading|completed|paused|checking|moving|error|unknown
+	LowLevelState      string // raw (native) state value returned by bt client. E.g. qb "forcedUP" / "stalledUP"
 	Atime              int64  // timestamp torrent added
 	Ctime              int64  // timestamp torrent completed. <=0 if not completed.
 	ActivityTime       int64  // timestamp of torrent latest activity (a chunk being downloaded / uploaded)
 	Category           string
 	SavePath           string
 	ContentPath        string
+	DownloadPath       string
 	Tags               []string
 	Downloaded         int64
 	DownloadSpeed      int64
 	DownloadSpeedLimit int64 // -1 means no limit
 	Uploaded           int64
 	UploadSpeed        int64
-	UploadedSpeedLimit int64 // -1 means no limit
+	UploadSpeedLimit   int64 // -1 means no limit
 	Size               int64 // size of torrent files that selected for downloading
 	SizeTotal          int64 // Total size of all file in the torrent (including unselected ones)
 	SizeCompleted      int64
 	Seeders            int64 // Cnt of seeders (including self client, if it's seeding), returned by tracker
 	Leechers           int64
+	ConnectedPeers     int64 // number of peers (seeds + leechers) currently connected to
+	Availability       float64 // distributed copies of torrent among connected peers (qb). -1 if unknown
+	Ratio              float64 // share ratio (Uploaded / Downloaded). 9999 if Downloaded == 0
+	PieceSize          int64   // piece size (bytes) of torrent. 0 if unknown
+	PieceCount         int64   // number of pieces of torrent. 0 if unknown
+	FilesTotal         int64   // number of files in torrent. 0 if unknown
+	FilesComplete      int64   // number of fully downloaded files. Valid only if FilesTotal > 0
+	Priority           int64   // queue position of torrent, 1 is the highest. 0 if unknown or not queued
+	NextAnnounceTime   int64   // timestamp of next scheduled tracker announce. 0 if unknown
 	Meta               map[string]int64
+	Comment            string // comment of torrent (.torrent file). Not all clients report it
+	SourceUrl          string // url where the torrent was obtained (parsed from comment), "" if unknown
 }
 
 The template render result will be trim spaced.
@@ -95,6 +111,7 @@ var (
 	largestFlag        bool
 	newestFlag         bool
 	showTrackers       bool
+	showRatio          bool
 	showFiles          bool
 	showInfoHashOnly   bool
 	partial            bool
@@ -115,6 +132,7 @@ var (
 	maxTorrentSizeStr  = ""
 	maxTotalSizeStr    = ""
 	excludes           = ""
+	metaConditions     []string
 	format             = ""
 	dense              = false
 	showAll            = false
@@ -139,6 +157,7 @@ func init() {
 	command.Flags().BoolVarP(&showInfoHashOnly, "show-info-hash-only", "", false, "Output torrents info hash only")
 	command.Flags().BoolVarP(&showSum, "sum", "", false, "Show torrents summary only")
 	command.Flags().BoolVarP(&showTrackers, "show-trackers", "", false, "Show torrent trackers info")
+	command.Flags().BoolVarP(&showRatio, "show-ratio", "", false, "Show torrent share ratio column")
 	command.Flags().BoolVarP(&showFiles, "show-files", "", false, "Show torrent content files info")
 	command.Flags().BoolVarP(&partial, "partial", "", false,
 		"Only showing torrents that are partially selected for downloading")
@@ -168,6 +187,9 @@ func init() {
 		`Filter torrent by it's content path. E.g. "/root/Downloads/[BDMV]Clannad"`)
 	command.Flags().StringVarP(&minTorrentSizeStr, "min-torrent-size", "", "-1", constants.HELP_ARG_MIN_TORRENT_SIZE)
 	command.Flags().StringVarP(&maxTorrentSizeStr, "max-torrent-size", "", "-1", constants.HELP_ARG_MAX_TORRENT_SIZE)
+	command.Flags().StringArrayVarP(&metaConditions, "meta", "", nil,
+		`Filter torrents by numeric meta value. Can be set multiple times, all conditions must match. `+
+			`E.g. "meta.addtime<1700000000". Torrent which does not have the meta only matches "!=" condition`)
 	command.Flags().StringVarP(&excludes, "exclude", "", "",
 		"Comma-separated list that torrent which name contains any one in the list will be skipped")
 	command.Flags().StringVarP(&format, "format", "", "", `Manually set the output format of each client torrent. `+
@@ -251,6 +273,14 @@ func show(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("--active-since must be before --not-active-since flag")
 	}
 	excludesList := util.SplitCsv(excludes)
+	metaFilter := &client.TorrentFilter{}
+	for _, expr := range metaConditions {
+		condition, err := client.ParseMetaCondition(expr)
+		if err != nil {
+			return fmt.Errorf("invalid meta: %w", err)
+		}
+		metaFilter.Meta = append(metaFilter.Meta, condition)
+	}
 	var outputTemplate *template.Template
 	if format != "" {
 		if outputTemplate, err = helper.GetTemplate(format); err != nil {
@@ -260,7 +290,7 @@ func show(cmd *cobra.Command, args []string) error {
 
 	hasFilterCondition := savePath != "" || savePathPrefix != "" || contentPath != "" ||
 		tracker != "" || minTorrentSize >= 0 || maxTorrentSize >= 0 || addedAfter > 0 || completedBefore > 0 ||
-		activeSince > 0 || notActiveSince > 0 || partial || excludes != "" || excludeTag != ""
+		activeSince > 0 || notActiveSince > 0 || partial || excludes != "" || excludeTag != "" || len(metaFilter.Meta) > 0
 	noConditionFlags := category == "" && tag == "" && filter == "" && !hasFilterCondition
 	var torrents []*client.Torrent
 	if showAll {
@@ -280,6 +310,15 @@ func show(cmd *cobra.Command, args []string) error {
 		if torrent == nil {
 			return fmt.Errorf("torrent %s not found", infoHashes[0])
 		}
+		if err := torrent.LoadFilesStats(clientInstance); err != nil {
+			log.Warnf("Failed to get torrent files stats: %v", err)
+		}
+		if err := torrent.LoadPieceGeometry(clientInstance); err != nil {
+			log.Warnf("Failed to get torrent piece geometry: %v", err)
+		}
+		if err := torrent.LoadNextAnnounceTime(clientInstance); err != nil {
+			log.Warnf("Failed to get torrent next announce time: %v", err)
+		}
 		torrent.Print()
 		if showTrackers {
 			fmt.Printf("\n")
@@ -321,7 +360,8 @@ func show(cmd *cobra.Command, args []string) error {
 				activeSince > 0 && t.ActivityTime < activeSince ||
 				notActiveSince > 0 && t.ActivityTime >= notActiveSince ||
 				excludeTag != "" && t.HasAnyTag(excludeTag) ||
-				partial && t.Size == t.SizeTotal {
+				partial && t.Size == t.SizeTotal ||
+				!metaFilter.Matches(t) {
 				return false
 			}
 			return true
@@ -415,7 +455,11 @@ func show(cmd *cobra.Command, args []string) error {
 		if showSum {
 			showSummary = 2
 		}
-		client.PrintTorrents(os.Stdout, torrents, "", showSummary, dense)
+		client.PrintTorrentsWithOptions(os.Stdout, torrents, &client.PrintTorrentsOptions{
+			ShowSum:   showSummary,
+			Dense:     dense,
+			ShowRatio: showRatio,
+		})
 	}
 	return nil
 }
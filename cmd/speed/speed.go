@@ -0,0 +1,47 @@
+package speed
+
+import (
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/cmd"
+)
+
+var command = &cobra.Command{
+	Use:   "speed {profile} {client}...",
+	Short: "Apply a speed profile to clients.",
+	Long: `Apply a speed profile to clients.
+{profile}: name of a speed profile defined in "speedProfiles" of config file.
+It sets the global download / upload speed limits of clients to the values of the profile.
+
+Example:
+  ptool speed night local remote # apply "night" speed profile to local and remote clients`,
+	Args: cobra.MatchAll(cobra.MinimumNArgs(2), cobra.OnlyValidArgs),
+	RunE: speed,
+}
+
+func init() {
+	cmd.RootCmd.AddCommand(command)
+}
+
+func speed(cmd *cobra.Command, args []string) error {
+	profileName := args[0]
+	var errs []error
+	for _, clientName := range args[1:] {
+		clientInstance, err := client.CreateClient(clientName)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to create client %s: %w", clientName, err))
+			continue
+		}
+		if err = client.ApplySpeedProfile(clientInstance, profileName); err != nil {
+			errs = append(errs, fmt.Errorf("client %s: %w", clientName, err))
+			continue
+		}
+		log.Infof("Applied speed profile %s to client %s", profileName, clientName)
+	}
+	return errors.Join(errs...)
+}
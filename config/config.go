@@ -39,6 +39,7 @@ const (
 	HR_TAG                     = "_hr"
 	PRIVATE_TAG                = "_private"
 	PUBLIC_TAG                 = "_public"
+	AUTOMATION_TAG             = "ptool" // added to all torrents added by ptool
 	STATS_FILENAME             = "ptool_stats.txt"
 	HISTORY_FILENAME           = "ptool_history"
 	SITE_TORRENTS_WIDTH        = 120 // min width for printing site torrents
@@ -90,6 +91,29 @@ type GroupConfigStruct struct {
 	Comment string   `yaml:"comment"`
 }
 
+// A named torrents query (filter), used by "--query" flag of commands. See client.RunSavedQuery.
+// All non-empty conditions must match.
+type QueryConfigStruct struct {
+	Name        string   `yaml:"name"`
+	StateFilter string   `yaml:"stateFilter"` // e.g. "_active", "seeding"
+	Category    string   `yaml:"category"`
+	Tag         string   `yaml:"tag"`
+	Tracker     string   `yaml:"tracker"`
+	Filter      string   `yaml:"filter"`
+	MinSize     string   `yaml:"minSize"` // e.g. "1GiB"
+	MaxSize     string   `yaml:"maxSize"`
+	Meta        []string `yaml:"meta"` // meta conditions, e.g. "addtime<1700000000". See client.ParseMetaCondition
+	Comment     string   `yaml:"comment"`
+}
+
+// A named global speed limits profile, applied by "ptool speed" command. See client.ApplySpeedProfile.
+type SpeedProfileConfigStruct struct {
+	Name               string `yaml:"name"`
+	DownloadSpeedLimit string `yaml:"downloadSpeedLimit"` // e.g. "10MiB". Empty or "0" means no limit
+	UploadSpeedLimit   string `yaml:"uploadSpeedLimit"`
+	Comment            string `yaml:"comment"`
+}
+
 type AliasConfigStruct struct {
 	Name        string `yaml:"name"`
 	Cmd         string `yaml:"cmd"`
@@ -124,6 +148,9 @@ type ClientConfigStruct struct {
 	BrushDefaultUploadSpeedLimitValue int64 ``
 	QbittorrentNoLogin                bool  `yaml:"qbittorrentNoLogin"`  // if set, will NOT send login request
 	QbittorrentNoLogout               bool  `yaml:"qbittorrentNoLogout"` // if set, will NOT send logout request
+
+	ServerToken    string `yaml:"serverToken"`    // if set, required by client/server HTTP API of this client
+	ServerReadOnly bool   `yaml:"serverReadOnly"` // if set (or token is not set), HTTP API of this client is read-only
 }
 
 type SiteConfigStruct struct {
@@ -243,31 +270,36 @@ type SiteConfigStruct struct {
 }
 
 type ConfigStruct struct {
-	Hushshell           bool                       `yaml:"hushshell"`
-	ShellMaxSuggestions int64                      `yaml:"shellMaxSuggestions"` // -1 禁用
-	ShellMaxHistory     int64                      `yaml:"shellMaxHistory"`     // -1 禁用
-	IyuuToken           string                     `yaml:"iyuuToken"`
-	ReseedUsername      string                     `yaml:"reseedUsername"`
-	ReseedPassword      string                     `yaml:"reseedPassword"`
-	IyuuDomain          string                     `yaml:"iyuuDomain"` // iyuu API 域名。默认使用 2025.iyuu.cn
-	SiteProxy           string                     `yaml:"siteProxy"`
-	SiteUserAgent       string                     `yaml:"siteUserAgent"`
-	SiteImpersonate     string                     `yaml:"siteImpersonate"`
-	SiteHttpHeaders     [][]string                 `yaml:"siteHttpHeaders"`
-	SiteJa3             string                     `yaml:"siteJa3"`
-	SiteTimeout         int64                      `yaml:"siteTimeout"`  // 访问网站超时时间(秒)
-	SiteInsecure        bool                       `yaml:"siteInsecure"` // 强制禁用所有站点 TLS 证书校验。
-	SiteH2Fingerprint   string                     `yaml:"siteH2Fingerprint"`
-	BrushEnableStats    bool                       `yaml:"brushEnableStats"`
-	Clients             []*ClientConfigStruct      `yaml:"clients"`
-	Sites               []*SiteConfigStruct        `yaml:"sites"`
-	Groups              []*GroupConfigStruct       `yaml:"groups"`
-	Aliases             []*AliasConfigStruct       `yaml:"aliases"`
-	Cookieclouds        []*CookiecloudConfigStruct `yaml:"cookieclouds"`
-	Comment             string                     `yaml:"comment"`
+	Hushshell           bool                        `yaml:"hushshell"`
+	ShellMaxSuggestions int64                       `yaml:"shellMaxSuggestions"` // -1 禁用
+	ShellMaxHistory     int64                       `yaml:"shellMaxHistory"`     // -1 禁用
+	IyuuToken           string                      `yaml:"iyuuToken"`
+	ReseedUsername      string                      `yaml:"reseedUsername"`
+	ReseedPassword      string                      `yaml:"reseedPassword"`
+	IyuuDomain          string                      `yaml:"iyuuDomain"` // iyuu API 域名。默认使用 2025.iyuu.cn
+	SiteProxy           string                      `yaml:"siteProxy"`
+	SiteUserAgent       string                      `yaml:"siteUserAgent"`
+	SiteImpersonate     string                      `yaml:"siteImpersonate"`
+	SiteHttpHeaders     [][]string                  `yaml:"siteHttpHeaders"`
+	SiteJa3             string                      `yaml:"siteJa3"`
+	SiteTimeout         int64                       `yaml:"siteTimeout"`  // 访问网站超时时间(秒)
+	SiteInsecure        bool                        `yaml:"siteInsecure"` // 强制禁用所有站点 TLS 证书校验。
+	SiteH2Fingerprint   string                      `yaml:"siteH2Fingerprint"`
+	BrushEnableStats    bool                        `yaml:"brushEnableStats"`
+	Clients             []*ClientConfigStruct       `yaml:"clients"`
+	Sites               []*SiteConfigStruct         `yaml:"sites"`
+	Groups              []*GroupConfigStruct        `yaml:"groups"`
+	Aliases             []*AliasConfigStruct        `yaml:"aliases"`
+	Queries             []*QueryConfigStruct        `yaml:"queries"`
+	SpeedProfiles       []*SpeedProfileConfigStruct `yaml:"speedProfiles"`
+	Cookieclouds        []*CookiecloudConfigStruct  `yaml:"cookieclouds"`
+	Comment             string                      `yaml:"comment"`
 	// 公网 BT 种子的分享率(Up/Dl)限制(到达后停止做种)。"add" 等命令添加公网种子到BT客户端时会自动应用此限制。
 	// 0 : unlimited。仅 qBittorrent 支持此选项。
 	PublicTorrentRatioLimit float64 `yaml:"publicTorrentRatioLimit"`
+	// tracker domain => site name. 用于根据种子 tracker 自动生成 "site:<name>" 标签。
+	// 未配置的 tracker 域名会尝试匹配已配置站点的 url / domains。
+	TrackerSites map[string]string `yaml:"trackerSites"`
 
 	ClientsEnabled []*ClientConfigStruct
 	SitesEnabled   []*SiteConfigStruct
@@ -278,29 +310,31 @@ type ConfigStruct struct {
 var DefaultConfigFs embed.FS
 
 var (
-	Timeout               = int64(0) // network(http) timeout. It has the highest priority. Set by --timeout global flag
-	VerboseLevel          = 0
-	InShell               = false
-	ConfigDir             = "" // "/root/.config/ptool"
-	ConfigFile            = "" // "ptool.toml"
-	DefaultConfigFile     = "" // set when start
-	ConfigName            = "" // "ptool"
-	ConfigType            = "" // "toml"
-	LockFile              = ""
-	Proxy                 = "" // proxy. It has the highest priority. Set by --proxy global flag
-	Tz                    = "" // override system timezone (TZ) used by the program. Set by --timezone global flag
-	GlobalLock            = false
-	LockOrExit            = false
-	Fork                  = false
-	Insecure              = false // Force disable all TLS / https cert verifications. Set by --insecure global flag
-	configData            *ConfigStruct
-	clientsConfigMap      = map[string]*ClientConfigStruct{}
-	sitesConfigMap        = map[string]*SiteConfigStruct{}
-	aliasesConfigMap      = map[string]*AliasConfigStruct{}
-	groupsConfigMap       = map[string]*GroupConfigStruct{}
-	cookiecloudsConfigMap = map[string]*CookiecloudConfigStruct{}
-	internalAliasesMap    = map[string]*AliasConfigStruct{}
-	once                  sync.Once
+	Timeout                = int64(0) // network(http) timeout. It has the highest priority. Set by --timeout global flag
+	VerboseLevel           = 0
+	InShell                = false
+	ConfigDir              = "" // "/root/.config/ptool"
+	ConfigFile             = "" // "ptool.toml"
+	DefaultConfigFile      = "" // set when start
+	ConfigName             = "" // "ptool"
+	ConfigType             = "" // "toml"
+	LockFile               = ""
+	Proxy                  = "" // proxy. It has the highest priority. Set by --proxy global flag
+	Tz                     = "" // override system timezone (TZ) used by the program. Set by --timezone global flag
+	GlobalLock             = false
+	LockOrExit             = false
+	Fork                   = false
+	Insecure               = false // Force disable all TLS / https cert verifications. Set by --insecure global flag
+	configData             *ConfigStruct
+	clientsConfigMap       = map[string]*ClientConfigStruct{}
+	sitesConfigMap         = map[string]*SiteConfigStruct{}
+	aliasesConfigMap       = map[string]*AliasConfigStruct{}
+	queriesConfigMap       = map[string]*QueryConfigStruct{}
+	speedProfilesConfigMap = map[string]*SpeedProfileConfigStruct{}
+	groupsConfigMap        = map[string]*GroupConfigStruct{}
+	cookiecloudsConfigMap  = map[string]*CookiecloudConfigStruct{}
+	internalAliasesMap     = map[string]*AliasConfigStruct{}
+	once                   sync.Once
 )
 
 var InternalAliases = []*AliasConfigStruct{
@@ -494,6 +528,20 @@ func Get() *ConfigStruct {
 			}
 			aliasesConfigMap[alias.Name] = alias
 		}
+		for _, query := range configData.Queries {
+			assertConfigItemNameIsValid("query", query.Name, query)
+			if queriesConfigMap[query.Name] != nil {
+				log.Fatalf("Invalid config file: duplicate query name %s found", query.Name)
+			}
+			queriesConfigMap[query.Name] = query
+		}
+		for _, speedProfile := range configData.SpeedProfiles {
+			assertConfigItemNameIsValid("speed profile", speedProfile.Name, speedProfile)
+			if speedProfilesConfigMap[speedProfile.Name] != nil {
+				log.Fatalf("Invalid config file: duplicate speed profile name %s found", speedProfile.Name)
+			}
+			speedProfilesConfigMap[speedProfile.Name] = speedProfile
+		}
 		for _, cookiecloud := range configData.Cookieclouds {
 			if cookiecloud.Name == "" {
 				continue
@@ -546,6 +594,22 @@ func GetAliasConfig(name string) *AliasConfigStruct {
 	return internalAliasesMap[name]
 }
 
+func GetQueryConfig(name string) *QueryConfigStruct {
+	Get()
+	if name == "" {
+		return nil
+	}
+	return queriesConfigMap[name]
+}
+
+func GetSpeedProfileConfig(name string) *SpeedProfileConfigStruct {
+	Get()
+	if name == "" {
+		return nil
+	}
+	return speedProfilesConfigMap[name]
+}
+
 func GetCookiecloudConfig(name string) *CookiecloudConfigStruct {
 	Get()
 	if name == "" {
@@ -729,6 +793,28 @@ func MatchSite(domain string, siteConfig *SiteConfigStruct) bool {
 	return false
 }
 
+// Return the site name of a tracker domain (e.g. "tracker.m-team.cc").
+// Lookup trackerSites config first (the domain itself or any of it's parent domains),
+// then fallback to the first enabled site that matches the domain. Return "" if not found.
+func SiteForTracker(domain string) string {
+	if domain == "" {
+		return ""
+	}
+	trackerSites := Get().TrackerSites
+	for d := domain; d != ""; {
+		if sitename := trackerSites[d]; sitename != "" {
+			return sitename
+		}
+		_, d, _ = strings.Cut(d, ".")
+	}
+	for _, siteConfig := range Get().SitesEnabled {
+		if MatchSite(domain, siteConfig) {
+			return siteConfig.GetName()
+		}
+	}
+	return ""
+}
+
 func (configData *ConfigStruct) UpdateSitesDerivative() {
 	configData.SitesEnabled = util.Filter(configData.Sites, func(s *SiteConfigStruct) bool {
 		return !s.Disabled
@@ -28,6 +28,8 @@ const HELP_ARG_TRACKER = `Filter torrents by tracker url or domain. Use "` +
 
 const HELP_ARG_FILTER_TORRENT = "Filter torrents by name"
 
+const HELP_ARG_QUERY = `Only select torrents that match the named query defined in "queries" of config file`
+
 const HELP_ARG_CATEGORY = `Filter torrents by category. Use "` + NONE + `" to select uncategoried torrents`
 const HELP_ARG_CATEGORY_XSEED = `Only xseed torrents that belongs to this category. Use "` +
 	NONE + `" to select uncategoried torrents`
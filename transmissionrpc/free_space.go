@@ -15,12 +15,19 @@ import (
 // FreeSpace allow to see how much free space is available in a client-specified folder.
 // https://github.com/transmission/transmission/blob/3.00/extras/rpc-spec.txt#L645
 func (c *Client) FreeSpace(ctx context.Context, path string) (freeSpace cunits.Bits, err error) {
+	space, err := c.FreeSpaceDetails(ctx, path)
+	if err == nil {
+		freeSpace = cunits.ImportInByte(float64(space.Size))
+	}
+	return
+}
+
+// FreeSpaceDetails is like FreeSpace, but returns the raw result, which also contains total size of the disk
+// on transmission 4.0+.
+func (c *Client) FreeSpaceDetails(ctx context.Context, path string) (space TransmissionFreeSpace, err error) {
 	payload := &transmissionFreeSpacePayload{Path: path}
-	var space TransmissionFreeSpace
 	if err = c.rpcCall(ctx, "free-space", payload, &space); err == nil {
-		if space.Path == path {
-			freeSpace = cunits.ImportInByte(float64(space.Size))
-		} else {
+		if space.Path != path {
 			err = fmt.Errorf("returned path '%s' does not match with requested path '%s'", space.Path, path)
 		}
 	} else {
@@ -36,6 +43,7 @@ type transmissionFreeSpacePayload struct {
 // TransmissionFreeSpace represents the freespace available in bytes for a specific path.
 // https://github.com/transmission/transmission/blob/3.00/extras/rpc-spec.txt#L653
 type TransmissionFreeSpace struct {
-	Path string `json:"path"`
-	Size int64  `json:"size-bytes"`
+	Path      string `json:"path"`
+	Size      int64  `json:"size-bytes"`
+	TotalSize int64  `json:"total_size"` // total size of the disk in bytes. transmission 4.0+
 }
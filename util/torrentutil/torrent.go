@@ -10,17 +10,21 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/anacrolix/torrent/bencode"
 	"github.com/anacrolix/torrent/metainfo"
+	infohash_v2 "github.com/anacrolix/torrent/types/infohash-v2"
 	"github.com/shibumi/go-pathspec"
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/term"
 
 	"github.com/sagan/ptool/client"
+	"github.com/sagan/ptool/config"
 	"github.com/sagan/ptool/constants"
 	"github.com/sagan/ptool/site/public"
 	"github.com/sagan/ptool/site/tpl"
@@ -32,6 +36,8 @@ type TorrentCommentMeta struct {
 	Tags     []string `json:"tags,omitempty"`
 	Comment  string   `json:"comment,omitempty"`
 	SavePath string   `json:"save_path,omitempty"`
+	// Url where the torrent was obtained. See client.ParseSourceUrlFromComment.
+	SourceUrl string `json:"source_url,omitempty"`
 }
 
 type TorrentMetaFile struct {
@@ -45,6 +51,8 @@ type TorrentMetaFile struct {
 
 type TorrentMeta struct {
 	InfoHash          string
+	InfoHashV1        string // v1 info-hash (sha1), "" if it's a pure v2 torrent
+	InfoHashV2        string // v2 info-hash (sha256), "" if it's a v1 only torrent
 	PiecesHash        string // sha1(torrent.info.pieces)
 	Trackers          []string
 	Size              int64
@@ -87,11 +95,14 @@ var (
 	ErrDifferentRootName = errors.New("this is multiple-file torrent. the torrent content files on disk " +
 		"has same contents with torrent meta, but they have DIFFERENT root folder name, " +
 		"so it can not be directly added to client as xseed torrent")
+	ErrNoReseedClient = errors.New("no client has torrent of same contents")
 )
 
 func (tm TorrentMeta) MarshalJSON() ([]byte, error) {
 	data := map[string]any{
 		"InfoHash":           tm.InfoHash,
+		"InfoHashV1":         tm.InfoHashV1,
+		"InfoHashV2":         tm.InfoHashV2,
 		"PiecesHash":         tm.PiecesHash,
 		"Trackers":           tm.Trackers,
 		"Size":               tm.Size,
@@ -139,6 +150,13 @@ func FromMetaInfo(metaInfo *metainfo.MetaInfo, info *metainfo.Info) (*TorrentMet
 		}
 		torrentMeta.Info = &_info
 	}
+	if torrentMeta.Info.HasV1() {
+		torrentMeta.InfoHashV1 = torrentMeta.InfoHash
+	}
+	if torrentMeta.Info.HasV2() {
+		infoHashV2 := infohash_v2.HashBytes(metaInfo.InfoBytes)
+		torrentMeta.InfoHashV2 = infoHashV2.HexString()
+	}
 	torrentMeta.PiecesHash = util.Sha1(torrentMeta.Info.Pieces)
 	info = torrentMeta.Info
 	piecesCnt := int64(info.NumPieces())
@@ -222,6 +240,9 @@ func (meta *TorrentMeta) EncodeComment(commentMeta *TorrentCommentMeta) error {
 	comment := ""
 	if existingCommentMeta := meta.DecodeComment(); existingCommentMeta != nil {
 		comment = existingCommentMeta.Comment
+		if commentMeta.SourceUrl == "" {
+			commentMeta.SourceUrl = existingCommentMeta.SourceUrl
+		}
 	} else {
 		comment = meta.MetaInfo.Comment
 	}
@@ -570,6 +591,44 @@ func (meta *TorrentMeta) XseedCheckWithClientTorrent(clientTorrentContents []*cl
 	return 0
 }
 
+// Find a client which has a completed torrent of the same contents (files & sizes) as meta, so meta can be added
+// to it as reseed (xseed) torrent using existing data. Clients are checked in order, a torrent which contents
+// equal exactly to meta (see XseedCheckWithClientTorrent) is preferred over one which contains more files.
+// Torrents of different root folder are not matched. Return ErrNoReseedClient if no client matches.
+func SelectClientForReseed(clientInstances []client.Client, meta *TorrentMeta) (
+	clientName string, matchedHash string, err error) {
+	for _, clientInstance := range clientInstances {
+		clientTorrents, err := clientInstance.GetTorrents("_done", "", true)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to get client %s torrents: %w", clientInstance.GetName(), err)
+		}
+		for _, clientTorrent := range clientTorrents {
+			// an exact match must be of same size; a larger one is only checked if no match is found yet.
+			if clientTorrent.Size < meta.Size || clientTorrent.Size > meta.Size && clientName != "" {
+				continue
+			}
+			clientTorrentContents, err := clientInstance.GetTorrentContents(clientTorrent.InfoHash)
+			if err != nil {
+				log.Debugf("failed to get client %s torrent %s contents: %v",
+					clientInstance.GetName(), clientTorrent.InfoHash, err)
+				continue
+			}
+			switch meta.XseedCheckWithClientTorrent(clientTorrentContents) {
+			case 0:
+				return clientInstance.GetName(), clientTorrent.InfoHash, nil
+			case 1:
+				if clientName == "" {
+					clientName, matchedHash = clientInstance.GetName(), clientTorrent.InfoHash
+				}
+			}
+		}
+	}
+	if clientName == "" {
+		return "", "", ErrNoReseedClient
+	}
+	return clientName, matchedHash, nil
+}
+
 func (meta *TorrentMeta) RootFiles() (rootFiles []string) {
 	if meta.RootDir != "" {
 		rootFiles = append(rootFiles, meta.RootDir)
@@ -976,3 +1035,63 @@ func infoBuildFromFilePath(info *metainfo.Info, root string, excludes []string,
 	}
 	return
 }
+
+var savePathPlaceholderRegex = regexp.MustCompile(`\{(date|category|site|tracker)\}`)
+
+// Return true if savePath is a template that contains any placeholder of ResolveSavePath.
+func IsSavePathTemplate(savePath string) bool {
+	return savePathPlaceholderRegex.MatchString(savePath)
+}
+
+// Expand placeholders in save path template:
+// {date}: date of now in "2006-01-02" format; {category}: category of option;
+// {site}: "site:" tag of option, or site of torrent's tracker (see config.SiteForTracker);
+// {tracker}: hostname of torrent's first tracker. tinfo can be nil (e.g. adding a magnet url).
+// Empty values are expanded to "none". Path separators and other restricted chars in values are replaced
+// and "." / ".." values are expanded to "none", so an expanded value never traverses out of it's parent folder.
+func ResolveSavePath(savePathTemplate string, tinfo *TorrentMeta, option *client.TorrentOption, now time.Time) string {
+	tracker := ""
+	if tinfo != nil && len(tinfo.Trackers) > 0 {
+		tracker = util.ParseUrlHostname(tinfo.Trackers[0])
+	}
+	site := ""
+	category := ""
+	if option != nil {
+		category = option.Category
+		for _, tag := range option.Tags {
+			if strings.HasPrefix(tag, "site:") {
+				site = tag[len("site:"):]
+				break
+			}
+		}
+	}
+	if site == "" {
+		site = config.SiteForTracker(tracker)
+	}
+	values := map[string]string{
+		"{date}":     now.Format("2006-01-02"),
+		"{category}": category,
+		"{site}":     site,
+		"{tracker}":  tracker,
+	}
+	return savePathPlaceholderRegex.ReplaceAllStringFunc(savePathTemplate, func(placeholder string) string {
+		value := strings.TrimSpace(constants.FilenameRestrictedCharacterReplacer.Replace(values[placeholder]))
+		if value == "" || value == "." || value == ".." {
+			value = constants.NONE
+		}
+		return value
+	})
+}
+
+// Return option.SavePath, with placeholders expanded (see ResolveSavePath) if it's a template.
+// Used by clients in AddTorrent.
+func ResolveOptionSavePath(torrentContent []byte, option *client.TorrentOption) string {
+	if !IsSavePathTemplate(option.SavePath) {
+		return option.SavePath
+	}
+	var tinfo *TorrentMeta
+	if !util.IsTorrentUrl(string(torrentContent)) {
+		tinfo, _ = ParseTorrent(torrentContent)
+	}
+	return ResolveSavePath(option.SavePath, tinfo, option, time.Now())
+}
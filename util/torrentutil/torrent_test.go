@@ -0,0 +1,41 @@
+package torrentutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sagan/ptool/client"
+)
+
+func TestResolveSavePath(t *testing.T) {
+	now := time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC)
+	tinfo := &TorrentMeta{Trackers: []string{"https://tracker.example.com/announce"}}
+	tests := []struct {
+		name     string
+		template string
+		tinfo    *TorrentMeta
+		option   *client.TorrentOption
+		want     string
+	}{
+		{"date", "/data/{date}", nil, nil, "/data/2024-03-05"},
+		{"category", "/data/{category}", nil, &client.TorrentOption{Category: "movies"}, "/data/movies"},
+		{"empty category", "/data/{category}", nil, &client.TorrentOption{}, "/data/none"},
+		{"nil option", "/data/{category}/{site}", nil, nil, "/data/none/none"},
+		{"dot dot category", "/data/{category}", nil, &client.TorrentOption{Category: ".."}, "/data/none"},
+		{"dot category", "/data/{category}", nil, &client.TorrentOption{Category: "."}, "/data/none"},
+		{"blank category", "/data/{category}", nil, &client.TorrentOption{Category: "  "}, "/data/none"},
+		{"slash category", "/data/{category}", nil, &client.TorrentOption{Category: "../../etc"},
+			"/data/..／..／etc"},
+		{"backslash category", `D:\data\{category}`, nil, &client.TorrentOption{Category: `..\..\Windows`},
+			`D:\data\..＼..＼Windows`},
+		{"site tag", "/data/{site}", nil, &client.TorrentOption{Tags: []string{"foo", "site:bar/baz"}},
+			"/data/bar／baz"},
+		{"tracker", "/data/{tracker}/{site}", tinfo, &client.TorrentOption{Tags: []string{"site:example"}},
+			"/data/tracker.example.com/example"},
+	}
+	for _, tt := range tests {
+		if got := ResolveSavePath(tt.template, tt.tinfo, tt.option, now); got != tt.want {
+			t.Errorf("ResolveSavePath(%q) %s = %q, want %q", tt.template, tt.name, got, tt.want)
+		}
+	}
+}